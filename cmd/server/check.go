@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/jwt"
+)
+
+// runCheck validates the configuration and the connection to the Document Server.
+// For every configured secret it reports whether the Document Server accepts it,
+// which shows the progress of a secret rotation. Returns the process exit code.
+func runCheck() int {
+	settings, err := config.LoadFromEnv()
+	if err != nil {
+		fmt.Printf("FAIL  configuration: %v\n", err)
+		return 1
+	}
+
+	failed := false
+	report := func(ok bool, format string, args ...interface{}) {
+		status := "OK  "
+		if !ok {
+			status = "FAIL"
+			failed = true
+		}
+		fmt.Printf("%s  %s\n", status, fmt.Sprintf(format, args...))
+	}
+
+	verr := settings.Validate()
+	report(verr == nil, "settings valid%s", errSuffix(verr))
+
+	if settings.DocumentServerURL == "" {
+		report(false, "%s is not set", config.EnvDocumentServerURL)
+		return 1
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	serverURL := strings.TrimSuffix(settings.DocumentServerURL, "/")
+
+	healthy, err := checkHealth(client, serverURL)
+	report(healthy, "Document Server healthcheck at %s%s", serverURL, errSuffix(err))
+
+	keyring := jwt.NewKeyring(settings.SecretKID, settings.DocumentServerSecret, settings.GetAcceptedSecrets())
+	if !keyring.Enabled() {
+		fmt.Println("WARN  JWT is disabled, requests to the Document Server are not signed")
+	} else {
+		manager := jwt.NewManager()
+		for i, key := range keyring.Keys() {
+			role := "primary"
+			if i > 0 {
+				role = "accepted"
+			}
+			accepted, err := checkSecret(client, serverURL, settings.GetJWTHeader(), manager, key)
+			label := fmt.Sprintf("%s secret %s", role, key.Fingerprint())
+			if key.ID != "" {
+				label += " (kid " + key.ID + ")"
+			}
+			if i == 0 {
+				report(accepted, "%s accepted by Document Server%s", label, errSuffix(err))
+			} else if accepted {
+				fmt.Printf("INFO  %s is still used by the Document Server\n", label)
+			} else {
+				fmt.Printf("INFO  %s is no longer used by the Document Server\n", label)
+			}
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// checkHealth calls the Document Server healthcheck endpoint
+func checkHealth(client *http.Client, serverURL string) (bool, error) {
+	resp, err := client.Get(serverURL + "/healthcheck")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return strings.TrimSpace(string(body)) == "true", nil
+}
+
+// checkSecret sends a signed "version" command and reports whether the
+// Document Server accepted the signature
+func checkSecret(client *http.Client, serverURL, header string, manager *jwt.Manager, key jwt.Key) (bool, error) {
+	command := map[string]interface{}{"c": "version"}
+	token, err := manager.Sign(key.Secret, command, key.ID)
+	if err != nil {
+		return false, err
+	}
+	command["token"] = token
+
+	body, _ := json.Marshal(command)
+	req, err := http.NewRequest("POST", serverURL+"/coauthoring/CommandService.ashx", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(header, "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Error int `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("invalid response: %w", err)
+	}
+	// Error 6 means the token was rejected
+	if result.Error != 0 {
+		return false, fmt.Errorf("command service error %d", result.Error)
+	}
+	return true, nil
+}
+
+// errSuffix formats an optional error for a report line
+func errSuffix(err error) string {
+	if err == nil {
+		return ""
+	}
+	return ": " + err.Error()
+}
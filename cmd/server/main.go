@@ -12,10 +12,19 @@ import (
 	"time"
 
 	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/convcache"
+	"onlyoffice-fnos/internal/editor"
 	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/fileid"
 	"onlyoffice-fnos/internal/format"
 	"onlyoffice-fnos/internal/jwt"
+	"onlyoffice-fnos/internal/notify"
+	"onlyoffice-fnos/internal/policy"
 	"onlyoffice-fnos/internal/server"
+	"onlyoffice-fnos/internal/session"
+	"onlyoffice-fnos/internal/thumbnail"
+	"onlyoffice-fnos/internal/userdata"
+	"onlyoffice-fnos/internal/users"
 )
 
 const (
@@ -31,6 +40,11 @@ func main() {
 	)
 	flag.Parse()
 
+	// "check" validates the configuration and Document Server connectivity
+	if flag.Arg(0) == "check" {
+		os.Exit(runCheck())
+	}
+
 	// Determine base URL
 	if *baseURL == "" {
 		*baseURL = fmt.Sprintf("http://localhost:%s", *port)
@@ -49,16 +63,73 @@ func main() {
 		log.Printf("  Document Server URL: %s", settings.DocumentServerURL)
 		if settings.DocumentServerSecret != "" {
 			log.Printf("  JWT Secret: configured")
+			if accepted := settings.GetAcceptedSecrets(); len(accepted) > 0 {
+				log.Printf("  JWT Accepted Secrets: %d", len(accepted))
+			}
+		}
+		if err := settings.Validate(); err != nil {
+			log.Printf("Warning: %v", err)
 		}
 		if settings.BaseURL != "" {
 			log.Printf("  Base URL (from env): %s", settings.BaseURL)
 		}
 	}
 
-	// Initialize modules
-	formatManager := format.NewManager()
+	// Initialize modules. A broken or missing configured format table would
+	// silently open documents in other modes than intended.
+	if settings.FormatsFile != "" {
+		if _, err := os.Stat(settings.FormatsFile); err != nil {
+			log.Fatalf("Invalid formats: %v", err)
+		}
+	}
+	formatManager, err := format.Load(settings.GetFormatsFile())
+	if err != nil {
+		log.Fatalf("Invalid formats: %v", err)
+	}
 	jwtManager := jwt.NewManager()
 	fileService := file.NewService("", 0) // No base path restriction, no size limit
+	if roots := settings.GetAllowedRoots(); len(roots) > 0 {
+		fileService.SetAllowedRoots(roots)
+		log.Printf("  Allowed roots: %v", roots)
+	}
+
+	// Save-error notifications with optional webhook and mail spool delivery
+	var channels []notify.Channel
+	if settings.NotifyWebhookURL != "" {
+		channels = append(channels, notify.NewWebhookChannel(settings.NotifyWebhookURL))
+	}
+	if settings.NotifyMailSpool != "" {
+		channels = append(channels, notify.NewMailSpoolChannel(settings.NotifyMailSpool, settings.NotifyMailTo))
+	}
+	notifier, err := notify.NewNotifier(settings.GetDataDir(), channels...)
+	if err != nil {
+		log.Printf("Warning: notifications disabled: %v", err)
+		notifier = nil
+	}
+
+	// Editor customization profile (global defaults and per-user overrides)
+	customization, err := editor.LoadProfile(settings.GetCustomizationFile())
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Permission policy restricting the open modes per user and folder. A
+	// broken or missing configured policy would lift every restriction.
+	if settings.PolicyFile != "" {
+		if _, err := os.Stat(settings.PolicyFile); err != nil {
+			log.Fatalf("Invalid policy: %v", err)
+		}
+	}
+	permissionPolicy, err := policy.Load(settings.GetPolicyFile())
+	if err != nil {
+		log.Fatalf("Invalid policy: %v", err)
+	}
+
+	// Users who can be mentioned in comments
+	userSources := []users.Source{&users.FileSource{Path: settings.GetUsersFile()}}
+	if settings.PasswdFile != "" {
+		userSources = append(userSources, &users.PasswdSource{Path: settings.PasswdFile})
+	}
 
 	// Create server configuration
 	serverConfig := &server.Config{
@@ -66,6 +137,15 @@ func main() {
 		FileService:   fileService,
 		FormatManager: formatManager,
 		JWTManager:    jwtManager,
+		Notifier:      notifier,
+		Sessions:      session.NewRegistry(settings.GetDataDir()),
+		UserData:      userdata.NewStore(settings.GetDataDir()),
+		Customization: customization,
+		Policy:        permissionPolicy,
+		Users:         users.NewDirectory(settings.GetDataDir(), userSources...),
+		FileIDs:       fileid.NewIndex(settings.GetDataDir()),
+		Thumbnails:    thumbnail.NewCache(settings.GetDataDir(), thumbnail.DefaultWorkers, thumbnail.DefaultMaxBytes),
+		Conversions:   convcache.NewCache(settings.GetDataDir(), settings.GetConversionCacheSize()),
 		BaseURL:       *baseURL,
 	}
 
@@ -2,7 +2,13 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -16,6 +22,51 @@ const (
 	EnvDocumentServerSecret = "DOCUMENT_SERVER_SECRET"
 	EnvBaseURL              = "BASE_URL"
 	EnvDocServerPath        = "DOC_SERVER_PATH"
+	EnvJWTHeader            = "JWT_HEADER"
+	EnvSecretKID            = "DOCUMENT_SERVER_SECRET_KID"
+	EnvAcceptedSecrets      = "DOCUMENT_SERVER_ACCEPTED_SECRETS"
+	EnvJWTExpiry            = "JWT_EXPIRY"
+	EnvDataDir              = "DATA_DIR"
+	EnvTemplatesDir         = "TEMPLATES_DIR"
+	EnvAllowedRoots         = "ALLOWED_ROOTS"
+	EnvFilesURL             = "FILES_URL"
+	EnvCustomizationFile    = "CUSTOMIZATION_FILE"
+	EnvPolicyFile           = "POLICY_FILE"
+	EnvFormatsFile          = "FORMATS_FILE"
+	EnvUsersFile            = "USERS_FILE"
+	EnvPasswdFile           = "PASSWD_FILE"
+	EnvUserHeader           = "USER_HEADER"
+	EnvUserNameHeader       = "USER_NAME_HEADER"
+	EnvUserEmailHeader      = "USER_EMAIL_HEADER"
+	EnvNotifyWebhookURL     = "NOTIFY_WEBHOOK_URL"
+	EnvNotifyMailSpool      = "NOTIFY_MAIL_SPOOL"
+	EnvNotifyMailTo         = "NOTIFY_MAIL_TO"
+	EnvConversionCacheSize  = "CONVERSION_CACHE_SIZE"
+	EnvConvertConflict      = "CONVERT_CONFLICT"
+	EnvConvertOriginal      = "CONVERT_ORIGINAL"
+	EnvConvertArchiveDir    = "CONVERT_ARCHIVE_DIR"
+)
+
+// Defaults for optional settings
+const (
+	DefaultDataDir             = "data"
+	DefaultJWTHeader           = "Authorization"
+	DefaultConversionCacheSize = 512 // Megabytes
+	DefaultConvertArchiveDir   = "originals"
+)
+
+// What to do when the target of a conversion exists
+const (
+	ConflictOverwrite = "overwrite" // Replace the existing file
+	ConflictRename    = "rename"    // Save as "name (converted).ext"
+	ConflictAsk       = "ask"       // Let the user choose on the convert page
+)
+
+// What to do with the original after a successful conversion
+const (
+	OriginalKeep    = "keep"
+	OriginalArchive = "archive" // Move into the archive folder
+	OriginalDelete  = "delete"
 )
 
 // Settings represents the application configuration
@@ -24,7 +75,30 @@ type Settings struct {
 	DocumentServerPubURL string `json:"documentServerPubUrl"` // Public/WAN URL for Document Server (optional, deprecated)
 	DocumentServerSecret string `json:"documentServerSecret"`
 	BaseURL              string `json:"baseUrl"`
-	DocServerPath        string `json:"docServerPath"` // Frontend path prefix for Document Server (e.g., "/doc-svr")
+	DocServerPath        string `json:"docServerPath"`     // Frontend path prefix for Document Server (e.g., "/doc-svr")
+	JWTHeader            string `json:"jwtHeader"`         // Header carrying the Document Server token (must match the server's JWT_HEADER)
+	SecretKID            string `json:"secretKid"`         // Optional key ID for the primary secret
+	AcceptedSecrets      string `json:"acceptedSecrets"`   // Comma-separated secrets still accepted for verification ("kid:secret" or "secret")
+	JWTExpiry            string `json:"jwtExpiry"`         // Lifetime of editor config tokens (e.g. "24h"), empty for no expiry
+	DataDir              string `json:"dataDir"`           // Directory for connector state (notifications, recovered files, ...)
+	TemplatesDir         string `json:"templatesDir"`      // Folder with user templates for new documents (optional)
+	AllowedRoots         string `json:"allowedRoots"`      // Comma-separated folders the connector may access (optional)
+	FilesURL             string `json:"filesUrl"`          // File manager URL for "Open file location", {folder} is replaced (optional)
+	CustomizationFile    string `json:"customizationFile"` // JSON editor customization profile, defaults to customization.json in DataDir
+	PolicyFile           string `json:"policyFile"`        // JSON rules restricting open modes per user and folder, defaults to policy.json in DataDir
+	FormatsFile          string `json:"formatsFile"`       // JSON overrides of the format table, defaults to formats.json in DataDir
+	UsersFile            string `json:"usersFile"`         // JSON list of users for comment mentions, defaults to users.json in DataDir
+	PasswdFile           string `json:"passwdFile"`        // passwd file of the NAS whose accounts can be mentioned (optional)
+	UserHeader           string `json:"userHeader"`        // Trusted proxy header carrying the user ID (optional)
+	UserNameHeader       string `json:"userNameHeader"`    // Trusted proxy header carrying the display name (optional)
+	UserEmailHeader      string `json:"userEmailHeader"`   // Trusted proxy header carrying the email address (optional)
+	NotifyWebhookURL     string `json:"notifyWebhookUrl"`
+	NotifyMailSpool      string `json:"notifyMailSpool"` // Directory where notification mails are spooled as .eml files
+	NotifyMailTo         string `json:"notifyMailTo"`
+	ConversionCacheSize  string `json:"conversionCacheSize"` // Size limit of cached conversions in megabytes, 0 disables the cache
+	ConvertConflict      string `json:"convertConflict"`     // overwrite, rename or ask when the conversion target exists, defaults to ask
+	ConvertOriginal      string `json:"convertOriginal"`     // keep, archive or delete the original after converting, defaults to keep
+	ConvertArchiveDir    string `json:"convertArchiveDir"`   // Archive folder, relative to the original's folder or absolute
 }
 
 // LoadFromEnv loads settings from environment variables.
@@ -47,5 +121,194 @@ func LoadFromEnv() (*Settings, error) {
 		DocumentServerSecret: secret,
 		BaseURL:              baseURL,
 		DocServerPath:        docServerPath,
+		JWTHeader:            os.Getenv(EnvJWTHeader),
+		SecretKID:            os.Getenv(EnvSecretKID),
+		AcceptedSecrets:      os.Getenv(EnvAcceptedSecrets),
+		JWTExpiry:            os.Getenv(EnvJWTExpiry),
+		DataDir:              os.Getenv(EnvDataDir),
+		TemplatesDir:         os.Getenv(EnvTemplatesDir),
+		AllowedRoots:         os.Getenv(EnvAllowedRoots),
+		FilesURL:             os.Getenv(EnvFilesURL),
+		CustomizationFile:    os.Getenv(EnvCustomizationFile),
+		PolicyFile:           os.Getenv(EnvPolicyFile),
+		FormatsFile:          os.Getenv(EnvFormatsFile),
+		UsersFile:            os.Getenv(EnvUsersFile),
+		PasswdFile:           os.Getenv(EnvPasswdFile),
+		UserHeader:           os.Getenv(EnvUserHeader),
+		UserNameHeader:       os.Getenv(EnvUserNameHeader),
+		UserEmailHeader:      os.Getenv(EnvUserEmailHeader),
+		NotifyWebhookURL:     os.Getenv(EnvNotifyWebhookURL),
+		NotifyMailSpool:      os.Getenv(EnvNotifyMailSpool),
+		NotifyMailTo:         os.Getenv(EnvNotifyMailTo),
+		ConversionCacheSize:  os.Getenv(EnvConversionCacheSize),
+		ConvertConflict:      os.Getenv(EnvConvertConflict),
+		ConvertOriginal:      os.Getenv(EnvConvertOriginal),
+		ConvertArchiveDir:    os.Getenv(EnvConvertArchiveDir),
 	}, nil
 }
+
+// GetDataDir returns the configured data directory or the default
+func (s *Settings) GetDataDir() string {
+	if s == nil || s.DataDir == "" {
+		return DefaultDataDir
+	}
+	return s.DataDir
+}
+
+// GetCustomizationFile returns the editor customization profile path
+func (s *Settings) GetCustomizationFile() string {
+	if s == nil || s.CustomizationFile == "" {
+		return filepath.Join(s.GetDataDir(), "customization.json")
+	}
+	return s.CustomizationFile
+}
+
+// GetPolicyFile returns the permission policy path
+func (s *Settings) GetPolicyFile() string {
+	if s == nil || s.PolicyFile == "" {
+		return filepath.Join(s.GetDataDir(), "policy.json")
+	}
+	return s.PolicyFile
+}
+
+// GetFormatsFile returns the format overrides path
+func (s *Settings) GetFormatsFile() string {
+	if s == nil || s.FormatsFile == "" {
+		return filepath.Join(s.GetDataDir(), "formats.json")
+	}
+	return s.FormatsFile
+}
+
+// GetUsersFile returns the path of the users list
+func (s *Settings) GetUsersFile() string {
+	if s == nil || s.UsersFile == "" {
+		return filepath.Join(s.GetDataDir(), "users.json")
+	}
+	return s.UsersFile
+}
+
+// GetConversionCacheSize returns the size limit of cached conversions in bytes, 0 if disabled
+func (s *Settings) GetConversionCacheSize() int64 {
+	if s == nil || s.ConversionCacheSize == "" {
+		return DefaultConversionCacheSize << 20
+	}
+	n, err := strconv.ParseInt(s.ConversionCacheSize, 10, 64)
+	if err != nil || n < 0 {
+		return DefaultConversionCacheSize << 20
+	}
+	return n << 20
+}
+
+// GetConvertConflict returns what to do when the conversion target exists
+func (s *Settings) GetConvertConflict() string {
+	if s == nil || !ValidConflict(s.ConvertConflict) {
+		return ConflictAsk
+	}
+	return s.ConvertConflict
+}
+
+// GetConvertOriginal returns what to do with the original after converting
+func (s *Settings) GetConvertOriginal() string {
+	if s == nil || !ValidOriginal(s.ConvertOriginal) {
+		return OriginalKeep
+	}
+	return s.ConvertOriginal
+}
+
+// GetConvertArchiveDir returns the folder originals are archived in
+func (s *Settings) GetConvertArchiveDir() string {
+	if s == nil || s.ConvertArchiveDir == "" {
+		return DefaultConvertArchiveDir
+	}
+	return s.ConvertArchiveDir
+}
+
+// ValidConflict returns true for a known conversion conflict behavior
+func ValidConflict(conflict string) bool {
+	return conflict == ConflictOverwrite || conflict == ConflictRename || conflict == ConflictAsk
+}
+
+// ValidOriginal returns true for a known handling of the original
+func ValidOriginal(original string) bool {
+	return original == OriginalKeep || original == OriginalArchive || original == OriginalDelete
+}
+
+// GetJWTHeader returns the configured JWT header name or the default
+func (s *Settings) GetJWTHeader() string {
+	if s == nil || s.JWTHeader == "" {
+		return DefaultJWTHeader
+	}
+	return s.JWTHeader
+}
+
+// GetAcceptedSecrets returns the list of additional verification secrets
+func (s *Settings) GetAcceptedSecrets() []string {
+	if s == nil || s.AcceptedSecrets == "" {
+		return nil
+	}
+	var result []string
+	for _, entry := range strings.Split(s.AcceptedSecrets, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// GetAllowedRoots returns the folders the connector may access, nil for no restriction
+func (s *Settings) GetAllowedRoots() []string {
+	if s == nil || s.AllowedRoots == "" {
+		return nil
+	}
+	var result []string
+	for _, root := range strings.Split(s.AllowedRoots, ",") {
+		if root = strings.TrimSpace(root); root != "" {
+			result = append(result, root)
+		}
+	}
+	return result
+}
+
+// GetFilesURL returns the file manager URL for folder, empty if not configured
+func (s *Settings) GetFilesURL(folder string) string {
+	if s == nil || s.FilesURL == "" {
+		return ""
+	}
+	return strings.ReplaceAll(s.FilesURL, "{folder}", url.QueryEscape(folder))
+}
+
+// GetJWTExpiry returns the lifetime of editor config tokens, 0 for no expiry
+func (s *Settings) GetJWTExpiry() time.Duration {
+	if s == nil || s.JWTExpiry == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s.JWTExpiry)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Validate checks settings that cannot be validated while loading
+func (s *Settings) Validate() error {
+	if s.JWTExpiry != "" {
+		if d, err := time.ParseDuration(s.JWTExpiry); err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %q: must be a positive duration such as 24h", EnvJWTExpiry, s.JWTExpiry)
+		}
+	}
+	if s.ConversionCacheSize != "" {
+		if n, err := strconv.ParseInt(s.ConversionCacheSize, 10, 64); err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q: must be a size in megabytes, 0 to disable", EnvConversionCacheSize, s.ConversionCacheSize)
+		}
+	}
+	if s.ConvertConflict != "" && !ValidConflict(s.ConvertConflict) {
+		return fmt.Errorf("invalid %s %q: must be overwrite, rename or ask", EnvConvertConflict, s.ConvertConflict)
+	}
+	if s.ConvertOriginal != "" && !ValidOriginal(s.ConvertOriginal) {
+		return fmt.Errorf("invalid %s %q: must be keep, archive or delete", EnvConvertOriginal, s.ConvertOriginal)
+	}
+	if len(s.GetAcceptedSecrets()) > 0 && s.DocumentServerSecret == "" {
+		return fmt.Errorf("%s requires %s to be set", EnvAcceptedSecrets, EnvDocumentServerSecret)
+	}
+	return nil
+}
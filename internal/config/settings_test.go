@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 // Unit test: LoadFromEnv returns error when no env vars are set
@@ -75,3 +76,103 @@ func TestLoadFromEnvPartialVars(t *testing.T) {
 		t.Errorf("BaseURL should be empty, got %q", settings.BaseURL)
 	}
 }
+
+// Unit test: Accepted secrets and token expiry are parsed and validated
+func TestSecretRotationSettings(t *testing.T) {
+	settings := &Settings{
+		DocumentServerSecret: "new-secret",
+		AcceptedSecrets:      " v1:old-secret , ,older-secret",
+		JWTExpiry:            "12h",
+	}
+
+	accepted := settings.GetAcceptedSecrets()
+	if len(accepted) != 2 || accepted[0] != "v1:old-secret" || accepted[1] != "older-secret" {
+		t.Errorf("unexpected accepted secrets: %q", accepted)
+	}
+	if settings.GetJWTExpiry() != 12*time.Hour {
+		t.Errorf("expected 12h expiry, got %v", settings.GetJWTExpiry())
+	}
+	if err := settings.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	settings.JWTExpiry = "tomorrow"
+	if settings.GetJWTExpiry() != 0 {
+		t.Error("invalid expiry should fall back to no expiry")
+	}
+	if err := settings.Validate(); err == nil {
+		t.Error("invalid expiry should fail validation")
+	}
+
+	settings.JWTExpiry = ""
+	settings.DocumentServerSecret = ""
+	if err := settings.Validate(); err == nil {
+		t.Error("accepted secrets without a primary secret should fail validation")
+	}
+}
+
+// Unit test: File manager URL substitutes the escaped folder
+func TestGetFilesURL(t *testing.T) {
+	var settings *Settings
+	if settings.GetFilesURL("/vol1") != "" {
+		t.Error("nil settings should have no files URL")
+	}
+
+	settings = &Settings{FilesURL: "https://nas.local:5666/files?path={folder}"}
+	if got := settings.GetFilesURL("/vol1/my docs"); got != "https://nas.local:5666/files?path=%2Fvol1%2Fmy+docs" {
+		t.Errorf("unexpected files URL %q", got)
+	}
+}
+
+// Unit test: Conversion cache size is given in megabytes, 0 disables the cache
+func TestGetConversionCacheSize(t *testing.T) {
+	var settings *Settings
+	if settings.GetConversionCacheSize() != DefaultConversionCacheSize<<20 {
+		t.Error("nil settings should use the default size")
+	}
+
+	settings = &Settings{ConversionCacheSize: "64"}
+	if settings.GetConversionCacheSize() != 64<<20 {
+		t.Errorf("expected 64 MB, got %d", settings.GetConversionCacheSize())
+	}
+	settings.ConversionCacheSize = "0"
+	if settings.GetConversionCacheSize() != 0 || settings.Validate() != nil {
+		t.Error("0 should disable the cache")
+	}
+	settings.ConversionCacheSize = "1GB"
+	if settings.GetConversionCacheSize() != DefaultConversionCacheSize<<20 {
+		t.Error("invalid size should fall back to the default")
+	}
+	if err := settings.Validate(); err == nil {
+		t.Error("invalid size should fail validation")
+	}
+}
+
+// Unit test: Conversion conflict and original handling default to ask and keep
+func TestConvertPolicySettings(t *testing.T) {
+	var settings *Settings
+	if settings.GetConvertConflict() != ConflictAsk || settings.GetConvertOriginal() != OriginalKeep {
+		t.Error("nil settings should ask and keep the original")
+	}
+	if settings.GetConvertArchiveDir() != DefaultConvertArchiveDir {
+		t.Error("nil settings should use the default archive folder")
+	}
+
+	settings = &Settings{ConvertConflict: "rename", ConvertOriginal: "archive", ConvertArchiveDir: "/vol1/archive"}
+	if settings.GetConvertConflict() != ConflictRename || settings.GetConvertOriginal() != OriginalArchive || settings.GetConvertArchiveDir() != "/vol1/archive" {
+		t.Errorf("unexpected conversion settings %+v", settings)
+	}
+	if err := settings.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	settings.ConvertConflict = "replace"
+	if settings.GetConvertConflict() != ConflictAsk || settings.Validate() == nil {
+		t.Error("unknown conflict behavior should fall back to ask and fail validation")
+	}
+	settings.ConvertConflict = ""
+	settings.ConvertOriginal = "trash"
+	if settings.GetConvertOriginal() != OriginalKeep || settings.Validate() == nil {
+		t.Error("unknown original handling should fall back to keep and fail validation")
+	}
+}
@@ -0,0 +1,42 @@
+package convcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"onlyoffice-fnos/internal/lru"
+)
+
+// NewCache creates the cache of converted documents below dataDir, keyed by
+// the content of their source. An empty dataDir or a limit of 0 disables
+// caching.
+func NewCache(dataDir string, maxBytes int64) *lru.Cache {
+	if dataDir == "" {
+		return lru.New("", 0)
+	}
+	return lru.New(filepath.Join(dataDir, "conversions"), maxBytes)
+}
+
+// Key identifies the conversion of a source content to a target format with
+// options such as the encoding. It is also a valid conversion API key, so
+// the Document Server can reuse its own result.
+func Key(contentHash, source, target string, options ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", contentHash, source, target)
+	for _, option := range options {
+		fmt.Fprintf(h, "\x00%s", option)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashContent returns the SHA-256 of a file content
+func HashContent(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,66 @@
+package convcache
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"onlyoffice-fnos/internal/lru"
+)
+
+// Unit test: a disabled cache passes conversions through
+func TestDisabledCache(t *testing.T) {
+	for _, c := range []*lru.Cache{nil, NewCache("", 10), NewCache(t.TempDir(), 0)} {
+		if c.Enabled() {
+			t.Error("expected the cache to be disabled")
+		}
+		rc, err := c.Store("a", io.NopCloser(strings.NewReader("aaaa")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := io.ReadAll(rc); string(data) != "aaaa" {
+			t.Errorf("expected the content to pass through, got %q", data)
+		}
+		if _, ok := c.Open("a"); ok {
+			t.Error("a disabled cache should not return conversions")
+		}
+	}
+}
+
+// Unit test: content passes through when the cache folder cannot be written
+func TestUnwritableCache(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "conversions"), []byte("not a folder"), 0644)
+	c := NewCache(dir, 10)
+
+	rc, err := c.Store("a", io.NopCloser(strings.NewReader("aaaa")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(rc); string(data) != "aaaa" {
+		t.Errorf("expected the content to pass through, got %q", data)
+	}
+	if _, ok := c.Open("a"); ok {
+		t.Error("nothing should be cached")
+	}
+}
+
+// Unit test: keys depend on the content, the formats and the options
+func TestKey(t *testing.T) {
+	hash, err := HashContent(strings.NewReader("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := Key(hash, "doc", "docx")
+	if len(key) != 64 || key != Key(hash, "doc", "docx") {
+		t.Errorf("expected a stable hex key, got %q", key)
+	}
+	other, _ := HashContent(strings.NewReader("other content"))
+	for _, k := range []string{Key(other, "doc", "docx"), Key(hash, "doc", "pdf"), Key(hash, "doc", "docx", "936")} {
+		if k == key {
+			t.Error("expected different conversions to get different keys")
+		}
+	}
+}
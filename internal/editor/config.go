@@ -3,6 +3,7 @@ package editor
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"path/filepath"
@@ -16,34 +17,88 @@ import (
 
 // EditorConfig represents the complete OnlyOffice editor configuration
 type EditorConfig struct {
-	Document     DocumentConfig     `json:"document"`
-	DocumentType string             `json:"documentType"` // word, cell, slide
-	EditorConfig EditorConfigInner  `json:"editorConfig"`
-	Token        string             `json:"token,omitempty"`
+	Document     DocumentConfig    `json:"document"`
+	DocumentType string            `json:"documentType"` // word, cell, slide
+	EditorConfig EditorConfigInner `json:"editorConfig"`
+	Type         string            `json:"type,omitempty"` // desktop, mobile, embedded
+	Token        string            `json:"token,omitempty"`
 }
 
 // DocumentConfig represents the document configuration
 type DocumentConfig struct {
-	FileType    string            `json:"fileType"`
-	Key         string            `json:"key"`
-	Title       string            `json:"title"`
-	URL         string            `json:"url"`
-	Permissions PermissionsConfig `json:"permissions"`
+	FileType      string               `json:"fileType"`
+	Key           string               `json:"key"`
+	Title         string               `json:"title"`
+	URL           string               `json:"url"`
+	Info          *DocumentInfo        `json:"info,omitempty"`
+	Permissions   PermissionsConfig    `json:"permissions"`
+	ReferenceData *ReferenceDataConfig `json:"referenceData,omitempty"`
 }
 
-// PermissionsConfig represents document permissions
+// ReferenceDataConfig identifies a file independently of its path, for
+// links between documents
+type ReferenceDataConfig struct {
+	FileKey    string `json:"fileKey"`    // Stable file identifier
+	InstanceID string `json:"instanceId"` // Connector that issued the identifier
+}
+
+// DocumentInfo represents the document details shown in the File menu
+type DocumentInfo struct {
+	Folder   string `json:"folder,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Uploaded string `json:"uploaded,omitempty"`
+	Favorite *bool  `json:"favorite,omitempty"` // Shows the favorite star when set
+}
+
+// PermissionsConfig represents document permissions. Review, comment and
+// form filling default to the edit permission when not set.
 type PermissionsConfig struct {
-	Edit     bool `json:"edit"`
-	Download bool `json:"download"`
-	Print    bool `json:"print"`
+	Edit      bool  `json:"edit"`
+	Download  bool  `json:"download"`
+	Print     bool  `json:"print"`
+	Review    *bool `json:"review,omitempty"`
+	Comment   *bool `json:"comment,omitempty"`
+	FillForms *bool `json:"fillForms,omitempty"`
 }
 
 // EditorConfigInner represents the inner editor configuration
 type EditorConfigInner struct {
-	CallbackURL string     `json:"callbackUrl"`
-	Lang        string     `json:"lang"`
-	Mode        string     `json:"mode"` // edit, view
-	User        UserConfig `json:"user"`
+	ActionLink    map[string]interface{} `json:"actionLink,omitempty"` // Scrolls to a comment or bookmark
+	CallbackURL   string                 `json:"callbackUrl"`
+	CoEditing     *CoEditingConfig       `json:"coEditing,omitempty"`
+	CreateURL     string                 `json:"createUrl,omitempty"`
+	Customization map[string]interface{} `json:"customization,omitempty"`
+	Lang          string                 `json:"lang"`
+	Mode          string                 `json:"mode"` // edit, view
+	Recent        []RecentConfig         `json:"recent,omitempty"`
+	Templates     []TemplateConfig       `json:"templates,omitempty"`
+	User          UserConfig             `json:"user"`
+}
+
+// CoEditingConfig represents the co-editing mode
+type CoEditingConfig struct {
+	Mode   string `json:"mode"`   // fast, strict
+	Change bool   `json:"change"` // Whether the user may switch the mode
+}
+
+// RecentConfig represents an entry of the "Open Recent" menu
+type RecentConfig struct {
+	Folder string `json:"folder"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// TemplateConfig represents an entry of the "Create New" menu
+type TemplateConfig struct {
+	Image string `json:"image,omitempty"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// GobackConfig represents the "Open file location" button
+type GobackConfig struct {
+	URL  string `json:"url"`
+	Text string `json:"text,omitempty"`
 }
 
 // UserConfig represents user information
@@ -52,15 +107,33 @@ type UserConfig struct {
 	Name string `json:"name"`
 }
 
+// Signer signs the claims of an editor configuration
+type Signer func(claims map[string]interface{}) (string, error)
+
 // ConfigRequest represents a request to build editor configuration
 type ConfigRequest struct {
-	FilePath    string
-	FileInfo    *file.FileInfo
-	UserID      string
-	UserName    string
-	Lang        string
-	BaseURL     string // Base URL for download and callback endpoints
-	JWTSecret   string
+	FilePath      string
+	FileInfo      *file.FileInfo
+	Key           string // Document key, derived from path and modification time if empty
+	UserID        string
+	UserName      string
+	Lang          string
+	BaseURL       string // Base URL for download and callback endpoints
+	JWTSecret     string
+	Signer        Signer        // Signs the config instead of JWTSecret (optional)
+	Mode          string        // Open mode, see policy.Mode*; empty for the format's preferred mode
+	Type          string        // Editor type: desktop, mobile or embedded
+	Profile       *Profile      // Customization profile (optional)
+	MacrosMode    string        // Macro policy for macro-enabled formats: enable, warn or disable (optional)
+	Goback        *GobackConfig // Used unless the profile sets goback (optional)
+	Info          *DocumentInfo
+	ReferenceData *ReferenceDataConfig // Stable identifier for links from other documents (optional)
+	CreateURL     string
+	Templates     []TemplateConfig
+	Recent        []RecentConfig
+	ActionLink    map[string]interface{} // Passed back by the editor in mention notifications (optional)
+	CallbackQuery url.Values             // Extra parameters of the callback URL (optional)
+	DownloadQuery url.Values             // Extra parameters of the download URL (optional)
 }
 
 // ConfigBuilder builds OnlyOffice editor configurations
@@ -89,21 +162,27 @@ func (b *ConfigBuilder) BuildConfig(req *ConfigRequest) (*EditorConfig, error) {
 		return nil, fmt.Errorf("unsupported file format: %s", req.FileInfo.Extension)
 	}
 
-	// Determine edit mode
-	canEdit := formatInfo.Editable
-	mode := "view"
-	if canEdit {
-		mode = "edit"
+	// Determine editor mode and permissions from the open mode
+	openMode := req.Mode
+	if openMode == "" {
+		openMode = FormatModes(formatInfo)[0]
 	}
+	mode, permissions, coEditing := modeConfig(openMode)
 
-	// Generate document key
-	docKey := b.generateDocumentKey(req.FilePath, req.FileInfo.ModTime)
+	// Join the given session or derive a key for a new one
+	docKey := req.Key
+	if docKey == "" {
+		docKey = b.generateDocumentKey(req.FilePath, req.FileInfo.ModTime)
+	}
 
 	// Build download URL
-	downloadURL := b.buildDownloadURL(req.BaseURL, req.FilePath)
+	downloadURL := DownloadURL(req.BaseURL, req.FilePath)
+	if len(req.DownloadQuery) > 0 {
+		downloadURL += "&" + req.DownloadQuery.Encode()
+	}
 
 	// Build callback URL
-	callbackURL := b.buildCallbackURL(req.BaseURL, req.FilePath)
+	callbackURL := b.buildCallbackURL(req.BaseURL, req.FilePath, req.CallbackQuery)
 
 	// Normalize language code
 	lang := b.normalizeLanguage(req.Lang)
@@ -118,33 +197,50 @@ func (b *ConfigBuilder) BuildConfig(req *ConfigRequest) (*EditorConfig, error) {
 		userName = "Anonymous User"
 	}
 
+	customization := req.Profile.Customization(userID)
+	if _, ok := customization["goback"]; !ok && req.Goback != nil {
+		customization["goback"] = map[string]interface{}{
+			"url":  req.Goback.URL,
+			"text": req.Goback.Text,
+		}
+	}
+	// The macro policy overrides the profile
+	if formatInfo.Macro && req.MacrosMode != "" {
+		customization["macrosMode"] = req.MacrosMode
+	}
+
 	config := &EditorConfig{
 		Document: DocumentConfig{
-			FileType: req.FileInfo.Extension,
-			Key:      docKey,
-			Title:    req.FileInfo.Name,
-			URL:      downloadURL,
-			Permissions: PermissionsConfig{
-				Edit:     canEdit,
-				Download: true,
-				Print:    true,
-			},
+			FileType:      req.FileInfo.Extension,
+			Key:           docKey,
+			Title:         req.FileInfo.Name,
+			URL:           downloadURL,
+			Info:          req.Info,
+			Permissions:   permissions,
+			ReferenceData: req.ReferenceData,
 		},
 		DocumentType: formatInfo.Type,
 		EditorConfig: EditorConfigInner{
-			CallbackURL: callbackURL,
-			Lang:        lang,
-			Mode:        mode,
+			ActionLink:    req.ActionLink,
+			CallbackURL:   callbackURL,
+			CoEditing:     coEditing,
+			CreateURL:     req.CreateURL,
+			Customization: customization,
+			Lang:          lang,
+			Mode:          mode,
+			Recent:        req.Recent,
+			Templates:     req.Templates,
 			User: UserConfig{
 				ID:   userID,
 				Name: userName,
 			},
 		},
+		Type: req.Type,
 	}
 
 	// Sign the configuration with JWT if secret is provided
 	if req.JWTSecret != "" {
-		token, err := b.signConfig(req.JWTSecret, config)
+		token, err := b.sign(req, config)
 		if err != nil {
 			return nil, fmt.Errorf("failed to sign config: %w", err)
 		}
@@ -163,8 +259,8 @@ func (b *ConfigBuilder) generateDocumentKey(filePath string, modTime time.Time)
 	return hex.EncodeToString(hash[:])[:20]
 }
 
-// buildDownloadURL builds the download URL for the document
-func (b *ConfigBuilder) buildDownloadURL(baseURL, filePath string) string {
+// DownloadURL builds the connector URL the Document Server downloads filePath from
+func DownloadURL(baseURL, filePath string) string {
 	if baseURL == "" {
 		// This should not happen if properly configured
 		// Log a warning in production
@@ -178,7 +274,7 @@ func (b *ConfigBuilder) buildDownloadURL(baseURL, filePath string) string {
 }
 
 // buildCallbackURL builds the callback URL for document saving
-func (b *ConfigBuilder) buildCallbackURL(baseURL, filePath string) string {
+func (b *ConfigBuilder) buildCallbackURL(baseURL, filePath string, query url.Values) string {
 	if baseURL == "" {
 		// This should not happen if properly configured
 		// Log a warning in production
@@ -188,6 +284,9 @@ func (b *ConfigBuilder) buildCallbackURL(baseURL, filePath string) string {
 	baseURL = strings.TrimSuffix(baseURL, "/")
 	// URL encode the file path
 	encodedPath := url.QueryEscape(filePath)
+	if len(query) > 0 {
+		return fmt.Sprintf("%s/callback?path=%s&%s", baseURL, encodedPath, query.Encode())
+	}
 	return fmt.Sprintf("%s/callback?path=%s", baseURL, encodedPath)
 }
 
@@ -209,14 +308,22 @@ func (b *ConfigBuilder) normalizeLanguage(lang string) string {
 	return lang
 }
 
-// signConfig signs the editor configuration with JWT
-func (b *ConfigBuilder) signConfig(secret string, config *EditorConfig) (string, error) {
-	claims := map[string]interface{}{
-		"document":     config.Document,
-		"documentType": config.DocumentType,
-		"editorConfig": config.EditorConfig,
+// sign signs an editor configuration or history data with JWT. The claims are
+// v exactly as it is sent to the editor, without the token.
+func (b *ConfigBuilder) sign(req *ConfigRequest, v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return "", err
+	}
+
+	if req.Signer != nil {
+		return req.Signer(claims)
 	}
-	return b.jwtManager.Sign(secret, claims)
+	return b.jwtManager.Sign(req.JWTSecret, claims)
 }
 
 // GetDocumentKey generates a document key for a given file path and modification time
@@ -0,0 +1,85 @@
+package editor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Profile holds editor customization defaults and per-user overrides.
+// Keys are passed through to editorConfig.customization, e.g.
+//
+//	{
+//	  "default": {"compactHeader": true, "uiTheme": "theme-dark", "zoom": 100},
+//	  "users": {"alice": {"unit": "inch", "features": {"spellcheck": false}}}
+//	}
+type Profile struct {
+	Default map[string]interface{}            `json:"default,omitempty"`
+	Users   map[string]map[string]interface{} `json:"users,omitempty"`
+}
+
+// DefaultCustomization returns the customization the connector relies on.
+// Forcesave makes Ctrl+S save the file immediately with callback status 6.
+func DefaultCustomization() map[string]interface{} {
+	return map[string]interface{}{
+		"autosave":  true,
+		"forcesave": true,
+	}
+}
+
+// LoadProfile reads a customization profile. A missing file yields an empty profile.
+func LoadProfile(path string) (*Profile, error) {
+	p := &Profile{}
+	if path == "" {
+		return p, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return p, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return &Profile{}, fmt.Errorf("invalid customization profile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Customization returns the connector defaults merged with the global
+// profile and the overrides of userID
+func (p *Profile) Customization(userID string) map[string]interface{} {
+	result := DefaultCustomization()
+	if p == nil {
+		return result
+	}
+	MergeCustomization(result, p.Default)
+	if user, ok := p.Users[userID]; ok {
+		MergeCustomization(result, user)
+	}
+	return result
+}
+
+// MergeCustomization merges src into dst. Nested objects such as "features"
+// or "logo" are merged key by key, other values are replaced.
+func MergeCustomization(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			merged := make(map[string]interface{}, len(dstMap))
+			MergeCustomization(merged, dstMap)
+			MergeCustomization(merged, srcMap)
+			dst[key] = merged
+			continue
+		}
+		if srcIsMap {
+			copied := make(map[string]interface{}, len(srcMap))
+			MergeCustomization(copied, srcMap)
+			dst[key] = copied
+			continue
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,54 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Unit test: Customization merges defaults, global profile and user overrides
+func TestProfileCustomization(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "customization.json")
+	os.WriteFile(path, []byte(`{
+		"default": {"compactHeader": true, "zoom": 100, "features": {"spellcheck": true, "tabStyle": "line"}},
+		"users": {"alice": {"zoom": 150, "features": {"spellcheck": false}}}
+	}`), 0644)
+
+	p, err := LoadProfile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := p.Customization("alice")
+	if c["forcesave"] != true || c["compactHeader"] != true || c["zoom"] != float64(150) {
+		t.Errorf("unexpected customization %v", c)
+	}
+	features := c["features"].(map[string]interface{})
+	if features["spellcheck"] != false || features["tabStyle"] != "line" {
+		t.Errorf("nested objects should be merged, got %v", features)
+	}
+
+	// Overrides of one user do not leak into the profile
+	if p.Customization("bob")["zoom"] != float64(100) {
+		t.Error("other users should get the global defaults")
+	}
+	if p.Default["features"].(map[string]interface{})["spellcheck"] != true {
+		t.Error("merging should not modify the profile")
+	}
+}
+
+// Unit test: Missing profiles are empty, invalid ones are reported
+func TestLoadProfileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	p, err := LoadProfile(filepath.Join(dir, "missing.json"))
+	if err != nil || p.Customization("any")["forcesave"] != true {
+		t.Errorf("missing profile should give defaults, got %v %v", p, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte("{"), 0644)
+	if _, err := LoadProfile(invalid); err == nil {
+		t.Error("expected error for invalid profile")
+	}
+}
@@ -0,0 +1,184 @@
+package editor
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/jwt"
+	"onlyoffice-fnos/internal/policy"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenRequest returns a fully populated request for name.ext
+func goldenRequest(name, ext string) *ConfigRequest {
+	favorite := true
+	return &ConfigRequest{
+		FilePath: "/vol1/docs/" + name + "." + ext,
+		FileInfo: &file.FileInfo{
+			Path:      "/vol1/docs/" + name + "." + ext,
+			Name:      name + "." + ext,
+			Extension: ext,
+			Size:      1024,
+			ModTime:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		UserID:    "alice",
+		UserName:  "Alice",
+		Lang:      "zh-CN",
+		BaseURL:   "http://connector:10099/",
+		JWTSecret: "golden-secret",
+		Profile: &Profile{
+			Default: map[string]interface{}{"compactHeader": true},
+			Users:   map[string]map[string]interface{}{"alice": {"uiTheme": "theme-dark"}},
+		},
+		Goback: &GobackConfig{URL: "/?folder=%2Fvol1%2Fdocs", Text: "打开文件位置"},
+		Info: &DocumentInfo{
+			Folder:   "/vol1/docs",
+			Uploaded: "2024-01-01 12:00",
+			Favorite: &favorite,
+		},
+		ReferenceData: &ReferenceDataConfig{
+			FileKey:    "3f2a9c0d1e4b5a6978c1d2e3",
+			InstanceID: "http://connector:10099",
+		},
+		CreateURL: "/new?folder=%2Fvol1%2Fdocs&type=word",
+		Templates: []TemplateConfig{
+			{Title: "空白", URL: "/new?folder=%2Fvol1%2Fdocs&type=word"},
+			{Title: "Report", URL: "/new?folder=%2Fvol1%2Fdocs&template=report.docx&type=word"},
+		},
+		Recent: []RecentConfig{
+			{Folder: "/vol1", Title: "other.xlsx", URL: "/editor?path=%2Fvol1%2Fother.xlsx"},
+		},
+	}
+}
+
+// Unit test: Generated configurations match the golden files in testdata
+func TestBuildConfigGolden(t *testing.T) {
+	builder := NewConfigBuilder(format.NewManager(), jwt.NewManager())
+
+	tests := []struct {
+		golden string
+		ext    string
+		mode   string
+		typ    string
+	}{
+		{"edit", "docx", policy.ModeEdit, "desktop"},
+		{"strict", "xlsx", policy.ModeStrict, "desktop"},
+		{"review", "docx", policy.ModeReview, "desktop"},
+		{"comment", "pptx", policy.ModeComment, "mobile"},
+		{"fillforms", "oform", "", "desktop"},
+		{"view", "pdf", "", "desktop"},
+		{"embedded", "docx", policy.ModeView, "embedded"},
+		{"macro", "xlsm", policy.ModeEdit, "desktop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			req := goldenRequest(tt.golden, tt.ext)
+			req.Mode = tt.mode
+			req.Type = tt.typ
+			req.MacrosMode = policy.MacrosDisable
+
+			config, err := builder.BuildConfig(req)
+			if err != nil {
+				t.Fatalf("failed to build config: %v", err)
+			}
+			checkGolden(t, tt.golden, config)
+		})
+	}
+}
+
+// Unit test: Version history objects match the golden file in testdata
+func TestBuildHistoryGolden(t *testing.T) {
+	builder := NewConfigBuilder(format.NewManager(), jwt.NewManager())
+	req := goldenRequest("history", "docx")
+
+	previous := &HistoryPrevious{FileType: "docx", Key: "previous-key", URL: "http://connector:10099/download?path=%2Fvol1%2Fdocs%2Fhistory.v1.docx"}
+	data, err := builder.BuildHistoryData(req, 2, "http://documentserver/cache/changes.zip", previous)
+	if err != nil {
+		t.Fatalf("failed to build history data: %v", err)
+	}
+	if _, err := jwt.NewManager().Verify(req.JWTSecret, data.Token); err != nil {
+		t.Fatalf("history data token should be valid: %v", err)
+	}
+
+	history := &History{
+		CurrentVersion: 2,
+		History: []HistoryEntry{
+			{Created: "2024-01-01 10:00:00", Key: "previous-key", User: &UserConfig{ID: "bob", Name: "Bob"}, Version: 1},
+			{
+				Changes:       json.RawMessage(`[{"created":"2024-01-01 12:00:00","user":{"id":"alice","name":"Alice"}}]`),
+				Created:       "2024-01-01 12:00:00",
+				Key:           data.Key,
+				ServerVersion: "8.0.0",
+				User:          &UserConfig{ID: "alice", Name: "Alice"},
+				Version:       2,
+			},
+		},
+	}
+	checkGolden(t, "history", map[string]interface{}{"history": history, "data": data})
+}
+
+// checkGolden compares the JSON of v with testdata/name.golden.json
+func checkGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal %s: %v", name, err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("output does not match %s (run with -update to refresh):\n%s", path, got)
+	}
+}
+
+// Unit test: The token signs exactly the configuration sent to the editor
+func TestBuildConfigSignsEmittedJSON(t *testing.T) {
+	jwtManager := jwt.NewManager()
+	builder := NewConfigBuilder(format.NewManager(), jwtManager)
+
+	var signed map[string]interface{}
+	req := goldenRequest("signed", "docx")
+	req.Type = "mobile"
+	req.Signer = func(claims map[string]interface{}) (string, error) {
+		signed = claims
+		return jwtManager.Sign(req.JWTSecret, claims)
+	}
+
+	config, err := builder.BuildConfig(req)
+	if err != nil {
+		t.Fatalf("failed to build config: %v", err)
+	}
+
+	claims, err := jwtManager.Verify(req.JWTSecret, config.Token)
+	if err != nil {
+		t.Fatalf("token should be valid: %v", err)
+	}
+
+	config.Token = ""
+	data, _ := json.Marshal(config)
+	var emitted map[string]interface{}
+	json.Unmarshal(data, &emitted)
+
+	if !reflect.DeepEqual(claims, emitted) || !reflect.DeepEqual(signed, emitted) {
+		t.Errorf("signed claims differ from emitted config:\nclaims:  %v\nemitted: %v", claims, emitted)
+	}
+}
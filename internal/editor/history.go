@@ -0,0 +1,70 @@
+package editor
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// History is the version list passed to refreshHistory
+type History struct {
+	CurrentVersion int            `json:"currentVersion"`
+	History        []HistoryEntry `json:"history"`
+}
+
+// HistoryEntry describes one version of a document
+type HistoryEntry struct {
+	Changes       json.RawMessage `json:"changes,omitempty"` // history.changes of the save callback
+	Created       string          `json:"created"`
+	Key           string          `json:"key"`
+	ServerVersion string          `json:"serverVersion,omitempty"`
+	User          *UserConfig     `json:"user,omitempty"`
+	Version       int             `json:"version"`
+}
+
+// HistoryData is the version passed to setHistoryData
+type HistoryData struct {
+	ChangesURL string           `json:"changesUrl,omitempty"`
+	FileType   string           `json:"fileType"`
+	Key        string           `json:"key"`
+	Previous   *HistoryPrevious `json:"previous,omitempty"`
+	Token      string           `json:"token,omitempty"`
+	URL        string           `json:"url"`
+	Version    int              `json:"version"`
+}
+
+// HistoryPrevious identifies the version a HistoryData compares against
+type HistoryPrevious struct {
+	FileType string `json:"fileType"`
+	Key      string `json:"key"`
+	URL      string `json:"url"`
+}
+
+// BuildHistoryData builds the setHistoryData object of the current version of
+// the document in req, signed like its editor configuration
+func (b *ConfigBuilder) BuildHistoryData(req *ConfigRequest, version int, changesURL string, previous *HistoryPrevious) (*HistoryData, error) {
+	if req == nil || req.FileInfo == nil {
+		return nil, fmt.Errorf("invalid history request")
+	}
+
+	key := req.Key
+	if key == "" {
+		key = b.generateDocumentKey(req.FilePath, req.FileInfo.ModTime)
+	}
+	data := &HistoryData{
+		ChangesURL: changesURL,
+		FileType:   req.FileInfo.Extension,
+		Key:        key,
+		Previous:   previous,
+		URL:        DownloadURL(req.BaseURL, req.FilePath),
+		Version:    version,
+	}
+
+	if req.JWTSecret != "" {
+		token, err := b.sign(req, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign history data: %w", err)
+		}
+		data.Token = token
+	}
+	return data, nil
+}
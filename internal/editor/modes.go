@@ -0,0 +1,61 @@
+package editor
+
+import (
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/policy"
+)
+
+// FormatModes returns the open modes a format supports, the preferred mode first
+func FormatModes(f *format.Format) []string {
+	switch {
+	case f.Editable, f.Convertible && f.EditInPlace:
+		modes := []string{policy.ModeEdit, policy.ModeStrict, policy.ModeReview, policy.ModeComment}
+		if f.FillForms {
+			modes = append(modes, policy.ModeFillForms)
+		}
+		return append(modes, policy.ModeView)
+	case f.FillForms && f.Extension == "pdf":
+		// Most PDF files are not forms, open them read-only unless filling is requested
+		return []string{policy.ModeView, policy.ModeFillForms}
+	case f.FillForms:
+		return []string{policy.ModeFillForms, policy.ModeView}
+	default:
+		return []string{policy.ModeView}
+	}
+}
+
+// modeConfig translates an open mode into editorConfig.mode, document.permissions
+// and editorConfig.coEditing (nil to keep the Document Server default)
+func modeConfig(mode string) (string, PermissionsConfig, *CoEditingConfig) {
+	permissions := PermissionsConfig{Download: true, Print: true}
+
+	switch mode {
+	case policy.ModeEdit:
+		permissions.Edit = true
+		return "edit", permissions, &CoEditingConfig{Mode: "fast", Change: true}
+	case policy.ModeStrict:
+		permissions.Edit = true
+		return "edit", permissions, &CoEditingConfig{Mode: "strict", Change: false}
+	case policy.ModeReview:
+		// Without edit permission the editor only allows tracked changes
+		permissions.Review = boolPtr(true)
+		permissions.Comment = boolPtr(true)
+		return "edit", permissions, nil
+	case policy.ModeComment:
+		permissions.Comment = boolPtr(true)
+		permissions.Review = boolPtr(false)
+		return "edit", permissions, nil
+	case policy.ModeFillForms:
+		permissions.FillForms = boolPtr(true)
+		permissions.Comment = boolPtr(false)
+		permissions.Review = boolPtr(false)
+		return "edit", permissions, nil
+	default:
+		return "view", permissions, nil
+	}
+}
+
+// boolPtr returns a pointer to b, for permissions that are only sent when set
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,83 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Move moves a file into a folder, creating the folder if needed, and returns
+// the new path. An existing file of the same name gets a numbered suffix.
+func (s *Service) Move(path, folder string) (string, error) {
+	fullPath, err := s.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrFileNotFound
+		}
+		if os.IsPermission(err) {
+			return "", ErrPermissionDenied
+		}
+		return "", err
+	}
+	if stat.IsDir() {
+		return "", ErrInvalidPath
+	}
+
+	fullFolder, err := s.resolvePath(folder)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(fullFolder, 0755); err != nil {
+		if os.IsPermission(err) {
+			return "", ErrPermissionDenied
+		}
+		return "", ErrSaveFailed
+	}
+
+	newPath, err := s.UniquePath(filepath.Join(folder, filepath.Base(path)))
+	if err != nil {
+		return "", err
+	}
+	fullNewPath, err := s.resolvePath(newPath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(fullPath, fullNewPath); err != nil {
+		if os.IsPermission(err) {
+			return "", ErrPermissionDenied
+		}
+		return "", ErrSaveFailed
+	}
+	return newPath, nil
+}
+
+// Remove deletes a file
+func (s *Service) Remove(path string) error {
+	fullPath, err := s.resolvePath(path)
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrFileNotFound
+		}
+		if os.IsPermission(err) {
+			return ErrPermissionDenied
+		}
+		return err
+	}
+	if stat.IsDir() {
+		return ErrInvalidPath
+	}
+	if err := os.Remove(fullPath); err != nil {
+		if os.IsPermission(err) {
+			return ErrPermissionDenied
+		}
+		return ErrSaveFailed
+	}
+	return nil
+}
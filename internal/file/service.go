@@ -2,9 +2,11 @@ package file
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -15,6 +17,7 @@ var (
 	ErrPermissionDenied   = errors.New("permission denied")
 	ErrSaveFailed         = errors.New("failed to save file")
 	ErrFileTooLarge       = errors.New("file size exceeds limit")
+	ErrFileExists         = errors.New("file already exists")
 )
 
 // FileInfo represents information about a file
@@ -24,6 +27,7 @@ type FileInfo struct {
 	Extension string    `json:"extension"`
 	Size      int64     `json:"size"`
 	ModTime   time.Time `json:"modTime"`
+	IsDir     bool      `json:"isDir,omitempty"`
 }
 
 // Service handles file operations for fnOS file system
@@ -32,6 +36,8 @@ type Service struct {
 	basePath string
 	// maxFileSize is the maximum allowed file size in bytes (0 = no limit)
 	maxFileSize int64
+	// allowedRoots restricts access to these folders (optional, empty = no restriction)
+	allowedRoots []string
 }
 
 // NewService creates a new FileService
@@ -42,6 +48,32 @@ func NewService(basePath string, maxFileSize int64) *Service {
 	}
 }
 
+// SetAllowedRoots restricts all file operations to the given folders
+func (s *Service) SetAllowedRoots(roots []string) {
+	s.allowedRoots = nil
+	for _, root := range roots {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		if !strings.HasPrefix(root, "/") {
+			root = "/" + root
+		}
+		s.allowedRoots = append(s.allowedRoots, filepath.Clean(root))
+	}
+}
+
+// GetAllowedRoots returns the folders file operations are restricted to
+func (s *Service) GetAllowedRoots() []string {
+	return s.allowedRoots
+}
+
+// CheckAccess returns ErrPermissionDenied if path is outside the allowed roots
+func (s *Service) CheckAccess(path string) error {
+	_, err := s.resolvePath(path)
+	return err
+}
+
 // GetFileInfo returns information about a file
 func (s *Service) GetFileInfo(path string) (*FileInfo, error) {
 	fullPath, err := s.resolvePath(path)
@@ -153,6 +185,216 @@ func (s *Service) SaveFile(path string, content io.Reader) error {
 	return nil
 }
 
+// Rename renames a file within its folder and returns the new path.
+// Existing files are never overwritten.
+func (s *Service) Rename(path, newName string) (string, error) {
+	if newName == "" || newName != filepath.Base(newName) || strings.HasPrefix(newName, ".") {
+		return "", ErrInvalidPath
+	}
+
+	fullPath, err := s.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	newPath := filepath.Join(filepath.Dir(path), newName)
+	fullNewPath, err := s.resolvePath(newPath)
+	if err != nil {
+		return "", err
+	}
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrFileNotFound
+		}
+		if os.IsPermission(err) {
+			return "", ErrPermissionDenied
+		}
+		return "", err
+	}
+	if stat.IsDir() {
+		return "", ErrInvalidPath
+	}
+
+	// A case-only rename on a case-insensitive file system finds the file itself
+	if newStat, err := os.Stat(fullNewPath); err == nil && !os.SameFile(stat, newStat) {
+		return "", ErrFileExists
+	}
+
+	if err := os.Rename(fullPath, fullNewPath); err != nil {
+		if os.IsPermission(err) {
+			return "", ErrPermissionDenied
+		}
+		return "", ErrSaveFailed
+	}
+
+	return newPath, nil
+}
+
+// CheckDir returns nil if path is an existing directory
+func (s *Service) CheckDir(path string) error {
+	fullPath, err := s.resolvePath(path)
+	if err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrFileNotFound
+		}
+		if os.IsPermission(err) {
+			return ErrPermissionDenied
+		}
+		return err
+	}
+
+	if !stat.IsDir() {
+		return ErrInvalidPath
+	}
+	return nil
+}
+
+// ListDir returns the visible entries of a directory, folders first.
+// Entry paths are in the same form as path so they can be passed back to the service.
+func (s *Service) ListDir(path string) ([]*FileInfo, error) {
+	if err := s.CheckDir(path); err != nil {
+		return nil, err
+	}
+	fullPath, _ := s.resolvePath(path)
+
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		if os.IsPermission(err) {
+			return nil, ErrPermissionDenied
+		}
+		return nil, err
+	}
+
+	clientPath := filepath.Clean("/" + strings.TrimPrefix(path, "/"))
+	result := make([]*FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || strings.HasPrefix(entry.Name(), "@") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fi := &FileInfo{
+			Path:    filepath.Join(clientPath, entry.Name()),
+			Name:    entry.Name(),
+			ModTime: info.ModTime(),
+			IsDir:   entry.IsDir(),
+		}
+		if !fi.IsDir {
+			fi.Extension = strings.ToLower(strings.TrimPrefix(filepath.Ext(entry.Name()), "."))
+			fi.Size = info.Size()
+		}
+		result = append(result, fi)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].IsDir != result[j].IsDir {
+			return result[i].IsDir
+		}
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result, nil
+}
+
+// systemDirs are virtual file systems that Search never walks into
+var systemDirs = map[string]bool{"/proc": true, "/sys": true, "/dev": true}
+
+// Search returns files below root whose name contains query (case-insensitive)
+// and which accept takes by path and extension, stopping after limit matches.
+// A nil accept takes every file. Hidden files and folders and system folders
+// are skipped.
+func (s *Service) Search(root, query string, accept func(path, ext string) bool, limit int) ([]*FileInfo, error) {
+	if err := s.CheckDir(root); err != nil {
+		return nil, err
+	}
+	fullRoot, _ := s.resolvePath(root)
+	clientRoot := filepath.Clean("/" + strings.TrimPrefix(root, "/"))
+	query = strings.ToLower(query)
+
+	var result []*FileInfo
+	err := filepath.WalkDir(fullRoot, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			// Skip unreadable folders
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		rel, _ := filepath.Rel(fullRoot, p)
+		clientPath := filepath.Join(clientRoot, rel)
+		if p != fullRoot && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "@") || d.IsDir() && systemDirs[clientPath]) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.Contains(strings.ToLower(name), query) {
+			return nil
+		}
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+		if accept != nil && !accept(clientPath, ext) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		result = append(result, &FileInfo{
+			Path:      clientPath,
+			Name:      name,
+			Extension: ext,
+			Size:      info.Size(),
+			ModTime:   info.ModTime(),
+		})
+		if limit > 0 && len(result) >= limit {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Exists returns true if a file or directory exists at path
+func (s *Service) Exists(path string) bool {
+	fullPath, err := s.resolvePath(path)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(fullPath)
+	return err == nil
+}
+
+// UniquePath returns path if nothing exists there yet, otherwise the first free
+// variant with a numbered suffix, e.g. "report (1).docx"
+func (s *Service) UniquePath(path string) (string, error) {
+	if _, err := s.resolvePath(path); err != nil {
+		return "", err
+	}
+	if !s.Exists(path) {
+		return path, nil
+	}
+
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for i := 1; i < 1000; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		if !s.Exists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", ErrSaveFailed
+}
+
 // resolvePath resolves and validates the file path
 func (s *Service) resolvePath(path string) (string, error) {
 	if path == "" {
@@ -168,26 +410,40 @@ func (s *Service) resolvePath(path string) (string, error) {
 	// Clean the path
 	cleanPath := filepath.Clean(path)
 
-	// If basePath is set, ensure the path is within it
-	if s.basePath != "" {
-		// If path is relative, join with basePath
-		if !filepath.IsAbs(cleanPath) {
-			cleanPath = filepath.Join(s.basePath, cleanPath)
+	// Enforce allowed roots on the path as seen by the client
+	if len(s.allowedRoots) > 0 {
+		allowed := false
+		for _, root := range s.allowedRoots {
+			if isWithin(cleanPath, root) {
+				allowed = true
+				break
+			}
 		}
+		if !allowed {
+			return "", ErrPermissionDenied
+		}
+	}
 
-		// Ensure the resolved path is within basePath
-		absPath, err := filepath.Abs(cleanPath)
+	// If basePath is set, ensure the path is within it
+	if s.basePath != "" {
+		absBase, err := filepath.Abs(s.basePath)
 		if err != nil {
 			return "", ErrInvalidPath
 		}
 
-		absBase, err := filepath.Abs(s.basePath)
+		// Paths outside basePath are interpreted relative to it
+		if !isWithin(cleanPath, absBase) {
+			cleanPath = filepath.Join(absBase, cleanPath)
+		}
+
+		// Ensure the resolved path is within basePath
+		absPath, err := filepath.Abs(cleanPath)
 		if err != nil {
 			return "", ErrInvalidPath
 		}
 
 		// Check for path traversal
-		if !strings.HasPrefix(absPath, absBase) {
+		if !isWithin(absPath, absBase) {
 			return "", ErrInvalidPath
 		}
 
@@ -198,6 +454,14 @@ func (s *Service) resolvePath(path string) (string, error) {
 	return cleanPath, nil
 }
 
+// isWithin reports whether path equals dir or lies below it
+func isWithin(path, dir string) bool {
+	if path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
 // GetBasePath returns the base path for file operations
 func (s *Service) GetBasePath() string {
 	return s.basePath
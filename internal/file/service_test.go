@@ -0,0 +1,113 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Unit test: Paths are resolved below the base path and cannot escape it
+func TestResolvePathWithBase(t *testing.T) {
+	base := t.TempDir()
+	s := NewService(base, 0)
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{"/vol1/report.docx", filepath.Join(base, "vol1/report.docx"), nil},
+		{"vol1/report.docx", filepath.Join(base, "vol1/report.docx"), nil},
+		{filepath.Join(base, "vol1/report.docx"), filepath.Join(base, "vol1/report.docx"), nil},
+		{base, base, nil},
+		// A sibling sharing the prefix of the base is outside of it
+		{base + "-other/report.docx", filepath.Join(base, base+"-other/report.docx"), nil},
+		{"/../../etc/passwd", filepath.Join(base, "etc/passwd"), nil},
+		{"", "", ErrInvalidPath},
+	}
+	for _, tt := range tests {
+		got, err := s.resolvePath(tt.path)
+		if err != tt.wantErr || got != tt.want {
+			t.Errorf("resolvePath(%q) = %q, %v; want %q, %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// Unit test: isWithin matches whole path components
+func TestIsWithin(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"/vol1", "/vol1", true},
+		{"/vol1/a.docx", "/vol1", true},
+		{"/vol1/a.docx", "/vol1/", true},
+		{"/vol10/a.docx", "/vol1", false},
+		{"/vol", "/vol1", false},
+		{"/vol1/a.docx", "/", true},
+	}
+	for _, tt := range tests {
+		if got := isWithin(tt.path, tt.dir); got != tt.want {
+			t.Errorf("isWithin(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
+
+// Unit test: Rename never replaces another file whose name differs only by case
+func TestRenameCaseOnly(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService("", 0)
+	lower := filepath.Join(dir, "report.docx")
+	upper := filepath.Join(dir, "Report.docx")
+	if err := os.WriteFile(lower, []byte("lower"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing only the case of a file's own name is allowed
+	newPath, err := s.Rename(lower, "REPORT.docx")
+	if err != nil {
+		t.Fatalf("case-only rename failed: %v", err)
+	}
+	if data, _ := os.ReadFile(newPath); string(data) != "lower" {
+		t.Errorf("renamed file has content %q", data)
+	}
+	if _, err := s.Rename(newPath, "report.docx"); err != nil {
+		t.Fatalf("case-only rename failed: %v", err)
+	}
+
+	if err := os.WriteFile(upper, []byte("upper"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(lower); string(data) != "lower" {
+		t.Skip("file system is case-insensitive")
+	}
+	if _, err := s.Rename(lower, "Report.docx"); err != ErrFileExists {
+		t.Fatalf("expected ErrFileExists, got %v", err)
+	}
+	if data, _ := os.ReadFile(upper); string(data) != "upper" {
+		t.Errorf("existing file was overwritten: %q", data)
+	}
+}
+
+// Unit test: Search counts only accepted files and skips system folders
+func TestSearch(t *testing.T) {
+	base := t.TempDir()
+	s := NewService(base, 0)
+	for _, name := range []string{"proc/1/report.docx", "vol1/a/report.mp4", "vol1/b/report.docx", "vol1/.hidden/report.docx"} {
+		os.MkdirAll(filepath.Join(base, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(base, name), []byte("x"), 0644)
+	}
+	docx := func(path, ext string) bool { return ext == "docx" }
+
+	result, err := s.Search("/", "REPORT", docx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].Path != "/vol1/b/report.docx" {
+		t.Fatalf("expected only /vol1/b/report.docx, got %+v", result)
+	}
+
+	if result, _ = s.Search("/vol1", "report", nil, 0); len(result) != 2 {
+		t.Errorf("expected every file without a filter, got %d", len(result))
+	}
+}
@@ -0,0 +1,69 @@
+package file
+
+import (
+	"io"
+	"os"
+
+	"onlyoffice-fnos/internal/format"
+)
+
+// Sniff returns the formats matching the content of a file, see format.Sniff
+func (s *Service) Sniff(path string) ([]string, error) {
+	var detected []string
+	err := s.inspect(path, func(r io.ReaderAt, size int64) {
+		detected = format.Sniff(r, size)
+	})
+	return detected, err
+}
+
+// IsEncrypted returns true if a file is password protected, see format.IsEncrypted
+func (s *Service) IsEncrypted(path string) (bool, error) {
+	var encrypted bool
+	err := s.inspect(path, func(r io.ReaderAt, size int64) {
+		encrypted = format.IsEncrypted(r, size)
+	})
+	return encrypted, err
+}
+
+// Head returns up to n bytes from the start of a file
+func (s *Service) Head(path string, n int) ([]byte, error) {
+	head := make([]byte, n)
+	var readErr error
+	err := s.inspect(path, func(r io.ReaderAt, size int64) {
+		n, readErr = r.ReadAt(head, 0)
+		if readErr == io.EOF {
+			readErr = nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return head[:n], readErr
+}
+
+// inspect opens a file for random access and passes it to fn
+func (s *Service) inspect(path string, fn func(r io.ReaderAt, size int64)) error {
+	fullPath, err := s.resolvePath(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrFileNotFound
+		}
+		if os.IsPermission(err) {
+			return ErrPermissionDenied
+		}
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	fn(f, stat.Size())
+	return nil
+}
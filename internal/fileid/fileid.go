@@ -0,0 +1,179 @@
+package fileid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Index assigns stable identifiers to files. Unlike paths, identifiers
+// survive renames, so documents can keep referring to each other.
+type Index struct {
+	mu         sync.Mutex
+	storePath  string
+	instanceID string
+	paths      map[string]string // ID -> path
+	ids        map[string]string // path -> ID
+}
+
+// NewIndex creates an Index. If dataDir is not empty, identifiers and the
+// instance ID are persisted.
+func NewIndex(dataDir string) *Index {
+	x := &Index{
+		paths: make(map[string]string),
+		ids:   make(map[string]string),
+	}
+	if dataDir == "" {
+		x.instanceID = newID()
+		return x
+	}
+
+	x.instanceID = loadInstanceID(filepath.Join(dataDir, "instance_id"))
+	x.storePath = filepath.Join(dataDir, "fileids.json")
+	data, err := os.ReadFile(x.storePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("File IDs: failed to read %s: %v", x.storePath, err)
+		}
+		return x
+	}
+	if err := json.Unmarshal(data, &x.paths); err != nil {
+		log.Printf("File IDs: failed to parse %s: %v", x.storePath, err)
+		x.paths = make(map[string]string)
+	}
+	for id, path := range x.paths {
+		x.ids[path] = id
+	}
+	return x
+}
+
+// InstanceID returns the random identifier of this connector, which tells its
+// file identifiers apart from those of other connectors
+func (x *Index) InstanceID() string {
+	return x.instanceID
+}
+
+// ID returns the identifier of path, assigning a new one on first use
+func (x *Index) ID(path string) string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if id, ok := x.ids[path]; ok {
+		return id
+	}
+	id := newID()
+	x.paths[id] = path
+	x.ids[path] = id
+	x.save()
+	return id
+}
+
+// Path returns the current path of an identifier
+func (x *Index) Path(id string) (string, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	path, ok := x.paths[id]
+	return path, ok
+}
+
+// Rename moves the identifiers of oldPath and the files below it to newPath.
+// Identifiers of files replaced at newPath are dropped.
+func (x *Index) Rename(oldPath, newPath string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	changed := false
+	for id, path := range x.paths {
+		if within(path, newPath) && !within(path, oldPath) {
+			delete(x.ids, path)
+			delete(x.paths, id)
+			changed = true
+		}
+	}
+	for id, path := range x.paths {
+		var moved string
+		switch {
+		case path == oldPath:
+			moved = newPath
+		case strings.HasPrefix(path, oldPath+"/"):
+			moved = newPath + strings.TrimPrefix(path, oldPath)
+		default:
+			continue
+		}
+		delete(x.ids, path)
+		x.paths[id] = moved
+		x.ids[moved] = id
+		changed = true
+	}
+	if changed {
+		x.save()
+	}
+}
+
+// Remove forgets the identifiers of a deleted file or folder
+func (x *Index) Remove(path string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	changed := false
+	for id, p := range x.paths {
+		if within(p, path) {
+			delete(x.ids, p)
+			delete(x.paths, id)
+			changed = true
+		}
+	}
+	if changed {
+		x.save()
+	}
+}
+
+// within reports whether path is dir or lies below it
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// save persists the identifiers, must be called with the lock held
+func (x *Index) save() {
+	if x.storePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(x.paths, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(x.storePath, data, 0644); err != nil {
+		log.Printf("File IDs: failed to write %s: %v", x.storePath, err)
+	}
+}
+
+// loadInstanceID reads the instance ID stored at path, creating it on first use
+func loadInstanceID(path string) string {
+	if data, err := os.ReadFile(path); err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id
+		}
+	}
+	id := newID()
+	if err := os.WriteFile(path, []byte(id+"\n"), 0644); err != nil {
+		log.Printf("File IDs: failed to write %s: %v", path, err)
+	}
+	return id
+}
+
+// newID generates a random file identifier
+func newID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,56 @@
+package fileid
+
+import "testing"
+
+// Unit test: Identifiers are stable, follow renames and survive a restart
+func TestIndex(t *testing.T) {
+	dir := t.TempDir()
+	x := NewIndex(dir)
+
+	id := x.ID("/vol1/finance/budget.xlsx")
+	if id == "" || x.ID("/vol1/finance/budget.xlsx") != id {
+		t.Fatalf("expected a stable ID, got %q", id)
+	}
+	if other := x.ID("/vol1/finance/sales.xlsx"); other == id {
+		t.Fatal("different files should get different IDs")
+	}
+
+	x.Rename("/vol1/finance/budget.xlsx", "/vol1/finance/budget-2024.xlsx")
+	if path, ok := x.Path(id); !ok || path != "/vol1/finance/budget-2024.xlsx" {
+		t.Errorf("ID should follow the rename, got %q %v", path, ok)
+	}
+
+	x.Rename("/vol1/finance", "/vol1/accounts")
+	if path, _ := x.Path(id); path != "/vol1/accounts/budget-2024.xlsx" {
+		t.Errorf("ID should follow the folder rename, got %q", path)
+	}
+
+	reloaded := NewIndex(dir)
+	if reloaded.ID("/vol1/accounts/budget-2024.xlsx") != id {
+		t.Error("IDs should survive a restart")
+	}
+	if reloaded.InstanceID() == "" || reloaded.InstanceID() != x.InstanceID() {
+		t.Errorf("instance ID should survive a restart, got %q and %q", x.InstanceID(), reloaded.InstanceID())
+	}
+	if NewIndex(t.TempDir()).InstanceID() == x.InstanceID() {
+		t.Error("instances should get different IDs")
+	}
+	if _, ok := reloaded.Path("missing"); ok {
+		t.Error("unknown IDs should not resolve")
+	}
+
+	// A file replaced by a rename loses its ID
+	replaced := reloaded.ID("/vol1/accounts/budget-2025.xlsx")
+	reloaded.Rename("/vol1/accounts/budget-2024.xlsx", "/vol1/accounts/budget-2025.xlsx")
+	if _, ok := reloaded.Path(replaced); ok {
+		t.Error("ID of a replaced file should not resolve")
+	}
+	if reloaded.ID("/vol1/accounts/budget-2025.xlsx") != id {
+		t.Error("renamed file should keep its ID")
+	}
+
+	reloaded.Remove("/vol1/accounts")
+	if _, ok := reloaded.Path(id); ok {
+		t.Error("ID of a deleted file should not resolve")
+	}
+}
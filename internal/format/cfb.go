@@ -0,0 +1,275 @@
+package format
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"unicode/utf16"
+)
+
+// Compound File Binary (OLE2) container used by legacy Office formats and
+// by password protected OOXML files
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+const (
+	cfbEndOfChain   = 0xFFFFFFFE
+	cfbMiniCutoff   = 4096 // Smaller streams are stored in the mini stream
+	cfbMiniSector   = 64   // Sector size of the mini stream
+	cfbHeaderLength = 512
+)
+
+var (
+	errInvalidCFB     = errors.New("invalid compound file")
+	errStreamNotFound = errors.New("stream not found")
+)
+
+// cfbEntry is a storage or stream of a compound file
+type cfbEntry struct {
+	name  string
+	start uint32
+	size  uint64
+}
+
+// cfbFile reads the directory and the streams of a compound file
+type cfbFile struct {
+	r          io.ReaderAt
+	size       int64
+	sectorSize int64
+	fat        []uint32
+	miniFAT    []uint32
+	mini       []uint32 // Sectors holding the mini stream
+	entries    []cfbEntry
+}
+
+// openCFB reads the allocation table and the directory of a compound file
+func openCFB(r io.ReaderAt, size int64) (*cfbFile, error) {
+	header := make([]byte, cfbHeaderLength)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	if string(header[:8]) != string(cfbSignature) {
+		return nil, errInvalidCFB
+	}
+
+	shift := binary.LittleEndian.Uint16(header[0x1E:])
+	if shift != 9 && shift != 12 {
+		return nil, errInvalidCFB
+	}
+	c := &cfbFile{r: r, size: size, sectorSize: int64(1) << shift}
+
+	// The FAT sectors are listed in the header and in a chain of DIFAT sectors
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		fatSectors = append(fatSectors, binary.LittleEndian.Uint32(header[0x4C+4*i:]))
+	}
+	difat := binary.LittleEndian.Uint32(header[0x44:])
+	seen := newChain(c.sectorCount())
+	for difat < cfbEndOfChain {
+		if !seen(difat) {
+			return nil, errInvalidCFB
+		}
+		sector, err := c.readSector(difat)
+		if err != nil {
+			return nil, err
+		}
+		for i := int64(0); i < c.sectorSize/4-1; i++ {
+			fatSectors = append(fatSectors, binary.LittleEndian.Uint32(sector[4*i:]))
+		}
+		difat = binary.LittleEndian.Uint32(sector[c.sectorSize-4:])
+	}
+
+	numFAT := binary.LittleEndian.Uint32(header[0x2C:])
+	if int64(numFAT) > int64(len(fatSectors)) {
+		return nil, errInvalidCFB
+	}
+	for _, id := range fatSectors[:numFAT] {
+		sector, err := c.readSector(id)
+		if err != nil {
+			return nil, err
+		}
+		for i := int64(0); i < c.sectorSize/4; i++ {
+			c.fat = append(c.fat, binary.LittleEndian.Uint32(sector[4*i:]))
+		}
+	}
+
+	// Directory entries are 128 bytes: UTF-16 name, name length, type,
+	// first sector and stream size. The root entry holds the mini stream.
+	miniStart := uint32(cfbEndOfChain)
+	err := c.walk(binary.LittleEndian.Uint32(header[0x30:]), func(sector []byte) bool {
+		for entry := sector; len(entry) >= 128; entry = entry[128:] {
+			nameLen := int(binary.LittleEndian.Uint16(entry[0x40:]))
+			if entry[0x42] == 5 && miniStart == cfbEndOfChain {
+				miniStart = binary.LittleEndian.Uint32(entry[0x74:])
+				continue
+			}
+			if kind := entry[0x42]; kind != 1 && kind != 2 || nameLen < 2 || nameLen > 64 {
+				continue
+			}
+			name := make([]uint16, nameLen/2-1)
+			for i := range name {
+				name[i] = binary.LittleEndian.Uint16(entry[2*i:])
+			}
+			size := binary.LittleEndian.Uint64(entry[0x78:])
+			if c.sectorSize == 512 {
+				size &= 0xFFFFFFFF // Version 3 files may leave garbage in the high bits
+			}
+			c.entries = append(c.entries, cfbEntry{
+				name:  string(utf16.Decode(name)),
+				start: binary.LittleEndian.Uint32(entry[0x74:]),
+				size:  size,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Streams below the cutoff are stored in 64 byte sectors of the mini
+	// stream, allocated by the mini FAT
+	if binary.LittleEndian.Uint16(header[0x20:]) == 6 && miniStart < cfbEndOfChain {
+		err = c.walk(binary.LittleEndian.Uint32(header[0x3C:]), func(sector []byte) bool {
+			for i := int64(0); i < c.sectorSize/4; i++ {
+				c.miniFAT = append(c.miniFAT, binary.LittleEndian.Uint32(sector[4*i:]))
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if c.mini, err = c.sectors(miniStart); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// readSector reads a sector by its number
+func (c *cfbFile) readSector(id uint32) ([]byte, error) {
+	offset := (int64(id) + 1) * c.sectorSize
+	if offset+c.sectorSize > c.size {
+		return nil, errInvalidCFB
+	}
+	sector := make([]byte, c.sectorSize)
+	_, err := c.r.ReadAt(sector, offset)
+	return sector, err
+}
+
+// sectorCount returns the number of sectors that fit in the file
+func (c *cfbFile) sectorCount() int {
+	return int(c.size / c.sectorSize)
+}
+
+// newChain returns a function that accepts each sector of a chain once. A
+// chain can neither visit a sector twice nor have more than limit sectors,
+// damaged files with cyclic or overlong chains are rejected.
+func newChain(limit int) func(id uint32) bool {
+	seen := make(map[uint32]bool)
+	return func(id uint32) bool {
+		if seen[id] || len(seen) >= limit {
+			return false
+		}
+		seen[id] = true
+		return true
+	}
+}
+
+// walk calls fn for the sectors of a chain until fn returns false
+func (c *cfbFile) walk(start uint32, fn func(sector []byte) bool) error {
+	seen := newChain(c.sectorCount())
+	for id := start; id < cfbEndOfChain; {
+		if !seen(id) {
+			return errInvalidCFB
+		}
+		sector, err := c.readSector(id)
+		if err != nil {
+			return err
+		}
+		if !fn(sector) {
+			return nil
+		}
+		if int64(id) >= int64(len(c.fat)) {
+			return errInvalidCFB
+		}
+		id = c.fat[id]
+	}
+	return nil
+}
+
+// sectors returns the sector numbers of a chain without reading them
+func (c *cfbFile) sectors(start uint32) ([]uint32, error) {
+	var ids []uint32
+	seen := newChain(c.sectorCount())
+	for id := start; id < cfbEndOfChain; id = c.fat[id] {
+		if !seen(id) || int64(id) >= int64(len(c.fat)) {
+			return nil, errInvalidCFB
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// has returns true if the compound file contains a storage or stream
+func (c *cfbFile) has(name string) bool {
+	for _, entry := range c.entries {
+		if entry.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readStream reads up to n bytes from the start of a stream
+func (c *cfbFile) readStream(name string, n int) ([]byte, error) {
+	for _, entry := range c.entries {
+		if entry.name != name {
+			continue
+		}
+		if uint64(n) > entry.size {
+			n = int(entry.size)
+		}
+		var data []byte
+		var err error
+		if entry.size < cfbMiniCutoff {
+			data, err = c.readMini(entry.start, n)
+		} else {
+			err = c.walk(entry.start, func(sector []byte) bool {
+				data = append(data, sector...)
+				return len(data) < n
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > n {
+			data = data[:n]
+		}
+		return data, nil
+	}
+	return nil, errStreamNotFound
+}
+
+// readMini reads at least n bytes of a chain in the mini stream
+func (c *cfbFile) readMini(start uint32, n int) ([]byte, error) {
+	if c.mini == nil {
+		return nil, errStreamNotFound
+	}
+	var data []byte
+	seen := newChain(len(c.miniFAT))
+	for id := start; id < cfbEndOfChain && len(data) < n; id = c.miniFAT[id] {
+		if !seen(id) || int64(id) >= int64(len(c.miniFAT)) {
+			return nil, errInvalidCFB
+		}
+		offset := int64(id) * cfbMiniSector
+		index := offset / c.sectorSize
+		if index >= int64(len(c.mini)) {
+			return nil, errInvalidCFB
+		}
+		sector := make([]byte, cfbMiniSector)
+		if _, err := c.r.ReadAt(sector, (int64(c.mini[index])+1)*c.sectorSize+offset%c.sectorSize); err != nil {
+			return nil, err
+		}
+		data = append(data, sector...)
+	}
+	return data, nil
+}
@@ -0,0 +1,38 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// Unit test: Cyclic sector chains of damaged compound files are rejected
+func TestCFBCyclicChain(t *testing.T) {
+	data := buildCFB(cfbStream{name: "WordDocument"})
+	if _, err := openCFB(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("valid file rejected: %v", err)
+	}
+
+	// The directory sector points back to itself
+	cyclic := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(cyclic[512+4*1:], 1)
+	if _, err := openCFB(bytes.NewReader(cyclic), int64(len(cyclic))); err != errInvalidCFB {
+		t.Errorf("expected errInvalidCFB for a cyclic directory, got %v", err)
+	}
+
+	// The last sector of the stream points back to its first
+	c, _ := openCFB(bytes.NewReader(data), int64(len(data)))
+	c.fat[9] = 2
+	c.entries[0].size = 1 << 20
+	if _, err := c.readStream("WordDocument", 1<<20); err != errInvalidCFB {
+		t.Errorf("expected errInvalidCFB for a cyclic stream, got %v", err)
+	}
+
+	// A DIFAT chain that loops
+	cyclic = append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(cyclic[0x44:], 2)
+	binary.LittleEndian.PutUint32(cyclic[3*512-4+512:], 2)
+	if _, err := openCFB(bytes.NewReader(cyclic), int64(len(cyclic))); err != errInvalidCFB {
+		t.Errorf("expected errInvalidCFB for a cyclic DIFAT, got %v", err)
+	}
+}
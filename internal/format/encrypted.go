@@ -0,0 +1,49 @@
+package format
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	wordIdent         = 0xA5EC // wIdent of the Word file information block
+	wordFlagEncrypted = 0x0100 // fEncrypted in the Word file information block
+	xlsBOF            = 0x0809 // Beginning of an Excel substream
+	xlsFilePass       = 0x002F // Excel encryption record, follows the first BOF
+)
+
+// IsEncrypted returns true if a document is password protected: OOXML
+// packages wrapped in an encrypted compound file, and legacy Word, Excel and
+// PowerPoint files with encryption enabled
+func IsEncrypted(r io.ReaderAt, size int64) bool {
+	c, err := openCFB(r, size)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case c.has("EncryptionInfo") && c.has("EncryptedPackage"):
+		return true
+	case c.has("EncryptedSummary"):
+		// PowerPoint keeps the encrypted document summary in its own stream
+		return true
+	case c.has("WordDocument"):
+		fib, err := c.readStream("WordDocument", 12)
+		return err == nil && len(fib) == 12 &&
+			binary.LittleEndian.Uint16(fib) == wordIdent &&
+			binary.LittleEndian.Uint16(fib[0x0A:])&wordFlagEncrypted != 0
+	}
+
+	for _, name := range []string{"Workbook", "Book"} {
+		records, err := c.readStream(name, 512)
+		if err != nil {
+			continue
+		}
+		if len(records) < 4 || binary.LittleEndian.Uint16(records) != xlsBOF {
+			return false
+		}
+		next := 4 + int(binary.LittleEndian.Uint16(records[2:]))
+		return next+2 <= len(records) && binary.LittleEndian.Uint16(records[next:]) == xlsFilePass
+	}
+	return false
+}
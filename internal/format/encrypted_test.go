@@ -0,0 +1,41 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Unit test: Password protected OOXML and legacy Office files are detected
+func TestIsEncrypted(t *testing.T) {
+	fib := func(flags byte) []byte {
+		return []byte{0xEC, 0xA5, 0xC1, 0x00, 0xC1, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, flags}
+	}
+	workbook := func(next byte) []byte {
+		return []byte{0x09, 0x08, 0x04, 0x00, 0x00, 0x06, 0x05, 0x00, next, 0x00, 0x00, 0x00}
+	}
+
+	tests := []struct {
+		name      string
+		content   []byte
+		encrypted bool
+	}{
+		{"ooxml", buildCFB(cfbStream{name: "EncryptionInfo"}, cfbStream{name: "EncryptedPackage"}), true},
+		{"doc", buildCFB(cfbStream{name: "WordDocument", data: fib(0x01)}), true},
+		{"plain doc", buildCFB(cfbStream{name: "WordDocument", data: fib(0x00)}), false},
+		{"xls", buildCFB(cfbStream{name: "Workbook", data: workbook(0x2F)}), true},
+		{"plain xls", buildCFB(cfbStream{name: "Workbook", data: workbook(0x42)}), false},
+		// Small workbooks are stored in the mini stream
+		{"small xls", buildCFB(cfbStream{name: "\x05SummaryInformation", data: make([]byte, 200), mini: true}, cfbStream{name: "Book", data: workbook(0x2F), mini: true}), true},
+		{"small plain xls", buildCFB(cfbStream{name: "Workbook", data: workbook(0x42), mini: true}), false},
+		{"small doc", buildCFB(cfbStream{name: "WordDocument", data: fib(0x01), mini: true}), true},
+		{"ppt", buildCFB(cfbStream{name: "PowerPoint Document"}, cfbStream{name: "EncryptedSummary"}), true},
+		{"plain ppt", buildCFB(cfbStream{name: "PowerPoint Document"}), false},
+		{"docx", []byte("PK\x03\x04"), false},
+	}
+
+	for _, tt := range tests {
+		if actual := IsEncrypted(bytes.NewReader(tt.content), int64(len(tt.content))); actual != tt.encrypted {
+			t.Errorf("%s: expected encrypted %v, got %v", tt.name, tt.encrypted, actual)
+		}
+	}
+}
@@ -17,6 +17,11 @@ type Format struct {
 	ViewOnly      bool   `json:"viewOnly"`
 	Convertible   bool   `json:"convertible"`
 	ConvertTarget string `json:"convertTarget"`
+	EditInPlace   bool   `json:"editInPlace"` // Convertible format opened in the editor, saved back in its own format
+	RoundTrip     bool   `json:"roundTrip"`   // Convertible format users may choose to edit in place
+	FillForms     bool   `json:"fillForms"`   // Form fields can be filled in
+	Macro         bool   `json:"macro"`       // May contain macros
+	MIME          string `json:"mime"`
 }
 
 // Manager handles file format operations
@@ -35,32 +40,76 @@ func NewManager() *Manager {
 
 // initFormats initializes the format mapping table
 func (m *Manager) initFormats() {
-	// Editable formats (OOXML)
-	m.formats["docx"] = &Format{Extension: "docx", Type: "word", Editable: true}
-	m.formats["xlsx"] = &Format{Extension: "xlsx", Type: "cell", Editable: true}
-	m.formats["pptx"] = &Format{Extension: "pptx", Type: "slide", Editable: true}
+	// Editable formats - Word (OOXML)
+	m.formats["docx"] = &Format{Extension: "docx", Type: "word", Editable: true, MIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document"}
+	m.formats["docm"] = &Format{Extension: "docm", Type: "word", Editable: true, Macro: true, MIME: "application/vnd.ms-word.document.macroEnabled.12"}
+	m.formats["dotx"] = &Format{Extension: "dotx", Type: "word", Editable: true, MIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.template"}
+	m.formats["dotm"] = &Format{Extension: "dotm", Type: "word", Editable: true, Macro: true, MIME: "application/vnd.ms-word.template.macroEnabled.12"}
+	m.formats["docxf"] = &Format{Extension: "docxf", Type: "word", Editable: true, FillForms: true, MIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document.docxf"} // Form template
+
+	// Editable formats - Cell (OOXML)
+	m.formats["xlsx"] = &Format{Extension: "xlsx", Type: "cell", Editable: true, MIME: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}
+	m.formats["xlsm"] = &Format{Extension: "xlsm", Type: "cell", Editable: true, Macro: true, MIME: "application/vnd.ms-excel.sheet.macroEnabled.12"}
+	m.formats["xltx"] = &Format{Extension: "xltx", Type: "cell", Editable: true, MIME: "application/vnd.openxmlformats-officedocument.spreadsheetml.template"}
+	m.formats["xltm"] = &Format{Extension: "xltm", Type: "cell", Editable: true, Macro: true, MIME: "application/vnd.ms-excel.template.macroEnabled.12"}
+
+	// Editable formats - Slide (OOXML)
+	m.formats["pptx"] = &Format{Extension: "pptx", Type: "slide", Editable: true, MIME: "application/vnd.openxmlformats-officedocument.presentationml.presentation"}
+	m.formats["pptm"] = &Format{Extension: "pptm", Type: "slide", Editable: true, Macro: true, MIME: "application/vnd.ms-powerpoint.presentation.macroEnabled.12"}
+	m.formats["potx"] = &Format{Extension: "potx", Type: "slide", Editable: true, MIME: "application/vnd.openxmlformats-officedocument.presentationml.template"}
+	m.formats["potm"] = &Format{Extension: "potm", Type: "slide", Editable: true, Macro: true, MIME: "application/vnd.ms-powerpoint.template.macroEnabled.12"}
+	m.formats["ppsx"] = &Format{Extension: "ppsx", Type: "slide", Editable: true, MIME: "application/vnd.openxmlformats-officedocument.presentationml.slideshow"}
+	m.formats["ppsm"] = &Format{Extension: "ppsm", Type: "slide", Editable: true, Macro: true, MIME: "application/vnd.ms-powerpoint.slideshow.macroEnabled.12"}
 
 	// Convertible formats - Word
-	m.formats["doc"] = &Format{Extension: "doc", Type: "word", Convertible: true, ConvertTarget: "docx"}
-	m.formats["odt"] = &Format{Extension: "odt", Type: "word", Convertible: true, ConvertTarget: "docx"}
-	m.formats["rtf"] = &Format{Extension: "rtf", Type: "word", Convertible: true, ConvertTarget: "docx"}
-	m.formats["txt"] = &Format{Extension: "txt", Type: "word", Convertible: true, ConvertTarget: "docx"}
+	m.formats["doc"] = &Format{Extension: "doc", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/msword"}
+	m.formats["dot"] = &Format{Extension: "dot", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/msword"}
+	m.formats["odt"] = &Format{Extension: "odt", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/vnd.oasis.opendocument.text"}
+	m.formats["ott"] = &Format{Extension: "ott", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/vnd.oasis.opendocument.text-template"}
+	m.formats["fodt"] = &Format{Extension: "fodt", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/vnd.oasis.opendocument.text-flat-xml"}
+	m.formats["rtf"] = &Format{Extension: "rtf", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/rtf"}
+	m.formats["txt"] = &Format{Extension: "txt", Type: "word", Convertible: true, ConvertTarget: "docx", RoundTrip: true, MIME: "text/plain"}
+	m.formats["md"] = &Format{Extension: "md", Type: "word", Convertible: true, ConvertTarget: "docx", RoundTrip: true, MIME: "text/markdown"}
+	m.formats["html"] = &Format{Extension: "html", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "text/html"}
+	m.formats["htm"] = &Format{Extension: "htm", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "text/html"}
+	m.formats["mht"] = &Format{Extension: "mht", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "message/rfc822"} // Web archive
+	m.formats["wps"] = &Format{Extension: "wps", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/vnd.ms-works"}
+	m.formats["wpt"] = &Format{Extension: "wpt", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/vnd.ms-works"}
+	m.formats["hwp"] = &Format{Extension: "hwp", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/x-hwp"}
+	m.formats["hwpx"] = &Format{Extension: "hwpx", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/hwp+zip"}
+	m.formats["pages"] = &Format{Extension: "pages", Type: "word", Convertible: true, ConvertTarget: "docx", MIME: "application/vnd.apple.pages"}
 
 	// Convertible formats - Cell
-	m.formats["xls"] = &Format{Extension: "xls", Type: "cell", Convertible: true, ConvertTarget: "xlsx"}
-	m.formats["ods"] = &Format{Extension: "ods", Type: "cell", Convertible: true, ConvertTarget: "xlsx"}
-	m.formats["csv"] = &Format{Extension: "csv", Type: "cell", Convertible: true, ConvertTarget: "xlsx"}
+	m.formats["xls"] = &Format{Extension: "xls", Type: "cell", Convertible: true, ConvertTarget: "xlsx", MIME: "application/vnd.ms-excel"}
+	m.formats["xlt"] = &Format{Extension: "xlt", Type: "cell", Convertible: true, ConvertTarget: "xlsx", MIME: "application/vnd.ms-excel"}
+	m.formats["xlsb"] = &Format{Extension: "xlsb", Type: "cell", Convertible: true, ConvertTarget: "xlsx", Macro: true, MIME: "application/vnd.ms-excel.sheet.binary.macroEnabled.12"}
+	m.formats["ods"] = &Format{Extension: "ods", Type: "cell", Convertible: true, ConvertTarget: "xlsx", MIME: "application/vnd.oasis.opendocument.spreadsheet"}
+	m.formats["ots"] = &Format{Extension: "ots", Type: "cell", Convertible: true, ConvertTarget: "xlsx", MIME: "application/vnd.oasis.opendocument.spreadsheet-template"}
+	m.formats["fods"] = &Format{Extension: "fods", Type: "cell", Convertible: true, ConvertTarget: "xlsx", MIME: "application/vnd.oasis.opendocument.spreadsheet-flat-xml"}
+	m.formats["csv"] = &Format{Extension: "csv", Type: "cell", Convertible: true, ConvertTarget: "xlsx", RoundTrip: true, MIME: "text/csv"}
+	m.formats["et"] = &Format{Extension: "et", Type: "cell", Convertible: true, ConvertTarget: "xlsx", MIME: "application/vnd.ms-excel"}
+	m.formats["ett"] = &Format{Extension: "ett", Type: "cell", Convertible: true, ConvertTarget: "xlsx", MIME: "application/vnd.ms-excel"}
+	m.formats["numbers"] = &Format{Extension: "numbers", Type: "cell", Convertible: true, ConvertTarget: "xlsx", MIME: "application/vnd.apple.numbers"}
 
 	// Convertible formats - Slide
-	m.formats["ppt"] = &Format{Extension: "ppt", Type: "slide", Convertible: true, ConvertTarget: "pptx"}
-	m.formats["odp"] = &Format{Extension: "odp", Type: "slide", Convertible: true, ConvertTarget: "pptx"}
+	m.formats["ppt"] = &Format{Extension: "ppt", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.ms-powerpoint"}
+	m.formats["pot"] = &Format{Extension: "pot", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.ms-powerpoint"}
+	m.formats["pps"] = &Format{Extension: "pps", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.ms-powerpoint"}
+	m.formats["odp"] = &Format{Extension: "odp", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.oasis.opendocument.presentation"}
+	m.formats["otp"] = &Format{Extension: "otp", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.oasis.opendocument.presentation-template"}
+	m.formats["fodp"] = &Format{Extension: "fodp", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.oasis.opendocument.presentation-flat-xml"}
+	m.formats["dps"] = &Format{Extension: "dps", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.ms-powerpoint"}
+	m.formats["dpt"] = &Format{Extension: "dpt", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.ms-powerpoint"}
+	m.formats["key"] = &Format{Extension: "key", Type: "slide", Convertible: true, ConvertTarget: "pptx", MIME: "application/vnd.apple.keynote"}
 
 	// View-only formats
-	m.formats["pdf"] = &Format{Extension: "pdf", Type: "word", ViewOnly: true}
-	m.formats["djvu"] = &Format{Extension: "djvu", Type: "word", ViewOnly: true}
-	m.formats["oxps"] = &Format{Extension: "oxps", Type: "word", ViewOnly: true}
-	m.formats["epub"] = &Format{Extension: "epub", Type: "word", ViewOnly: true}
-	m.formats["fb2"] = &Format{Extension: "fb2", Type: "word", ViewOnly: true}
+	m.formats["pdf"] = &Format{Extension: "pdf", Type: "word", ViewOnly: true, FillForms: true, MIME: "application/pdf"}
+	m.formats["oform"] = &Format{Extension: "oform", Type: "word", ViewOnly: true, FillForms: true, MIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document.oform"} // Fillable form
+	m.formats["djvu"] = &Format{Extension: "djvu", Type: "word", ViewOnly: true, MIME: "image/vnd.djvu"}
+	m.formats["xps"] = &Format{Extension: "xps", Type: "word", ViewOnly: true, MIME: "application/vnd.ms-xpsdocument"}
+	m.formats["oxps"] = &Format{Extension: "oxps", Type: "word", ViewOnly: true, MIME: "application/oxps"}
+	m.formats["epub"] = &Format{Extension: "epub", Type: "word", ViewOnly: true, MIME: "application/epub+zip"}
+	m.formats["fb2"] = &Format{Extension: "fb2", Type: "word", ViewOnly: true, MIME: "application/x-fictionbook+xml"}
 }
 
 // GetFormat returns the format information for a given extension
@@ -106,6 +155,26 @@ func (m *Manager) GetConvertTarget(extension string) string {
 	return f.ConvertTarget
 }
 
+// IsEditableInPlace returns true if a convertible format is edited without
+// converting the file first
+func (m *Manager) IsEditableInPlace(extension string) bool {
+	f, ok := m.GetFormat(extension)
+	if !ok {
+		return false
+	}
+	return f.Convertible && f.EditInPlace
+}
+
+// CanRoundTrip returns true if a convertible format may be edited in place
+// on request, with saves converted back to it
+func (m *Manager) CanRoundTrip(extension string) bool {
+	f, ok := m.GetFormat(extension)
+	if !ok {
+		return false
+	}
+	return f.Convertible && (f.RoundTrip || f.EditInPlace)
+}
+
 // GetDocumentType returns the document type (word, cell, slide) for a given extension
 func (m *Manager) GetDocumentType(extension string) string {
 	f, ok := m.GetFormat(extension)
@@ -115,6 +184,33 @@ func (m *Manager) GetDocumentType(extension string) string {
 	return f.Type
 }
 
+// IsMacroEnabled returns true if the format may contain macros
+func (m *Manager) IsMacroEnabled(extension string) bool {
+	f, ok := m.GetFormat(extension)
+	if !ok {
+		return false
+	}
+	return f.Macro
+}
+
+// GetMIMEType returns the MIME type of a format, empty if unknown
+func (m *Manager) GetMIMEType(extension string) string {
+	f, ok := m.GetFormat(extension)
+	if !ok {
+		return ""
+	}
+	return f.MIME
+}
+
+// CanFillForms checks if a format supports form filling
+func (m *Manager) CanFillForms(extension string) bool {
+	f, ok := m.GetFormat(extension)
+	if !ok {
+		return false
+	}
+	return f.FillForms
+}
+
 // GetAllConvertibleFormats returns all formats that can be converted
 func (m *Manager) GetAllConvertibleFormats() []*Format {
 	var result []*Format
@@ -114,3 +114,57 @@ func TestExtensionNormalization(t *testing.T) {
 		}
 	}
 }
+
+// Unit test: Verify macro, template and show formats with their MIME types
+func TestMacroAndTemplateFormats(t *testing.T) {
+	m := NewManager()
+
+	tests := []struct {
+		ext      string
+		editable bool
+		macro    bool
+		mime     string
+	}{
+		{"docm", true, true, "application/vnd.ms-word.document.macroEnabled.12"},
+		{"dotx", true, false, "application/vnd.openxmlformats-officedocument.wordprocessingml.template"},
+		{"xlsm", true, true, "application/vnd.ms-excel.sheet.macroEnabled.12"},
+		{"xlsb", false, true, "application/vnd.ms-excel.sheet.binary.macroEnabled.12"},
+		{"ppsx", true, false, "application/vnd.openxmlformats-officedocument.presentationml.slideshow"},
+		{"docx", true, false, "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	}
+
+	for _, tt := range tests {
+		if m.IsEditable(tt.ext) != tt.editable {
+			t.Errorf("format %s editable should be %v", tt.ext, tt.editable)
+		}
+		if m.IsMacroEnabled(tt.ext) != tt.macro {
+			t.Errorf("format %s macro should be %v", tt.ext, tt.macro)
+		}
+		if actual := m.GetMIMEType(tt.ext); actual != tt.mime {
+			t.Errorf("format %s should have MIME type %s, got %s", tt.ext, tt.mime, actual)
+		}
+	}
+
+	if m.GetConvertTarget("xlsb") != "xlsx" || m.GetConvertTarget("pps") != "pptx" {
+		t.Error("binary formats should convert to OOXML")
+	}
+}
+
+// Unit test: Verify web and text formats are converted and text formats round trip
+func TestTextFormats(t *testing.T) {
+	m := NewManager()
+
+	for _, ext := range []string{"md", "html", "htm", "mht", "txt", "csv"} {
+		if !m.IsConvertible(ext) || m.IsEditableInPlace(ext) {
+			t.Errorf("format %s should be converted by default", ext)
+		}
+	}
+	for _, ext := range []string{"md", "txt", "csv"} {
+		if !m.CanRoundTrip(ext) {
+			t.Errorf("format %s should round trip", ext)
+		}
+	}
+	if m.CanRoundTrip("html") || m.CanRoundTrip("docx") {
+		t.Error("html and docx should not round trip")
+	}
+}
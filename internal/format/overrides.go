@@ -0,0 +1,163 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Override changes a built-in format or adds a new one. Unset fields keep
+// the built-in value. Setting editable, viewOnly or convertible to true
+// clears the other two unless they are set as well.
+type Override struct {
+	Extension     string  `json:"extension"`
+	Type          *string `json:"type,omitempty"`
+	Editable      *bool   `json:"editable,omitempty"`
+	ViewOnly      *bool   `json:"viewOnly,omitempty"`
+	Convertible   *bool   `json:"convertible,omitempty"`
+	ConvertTarget *string `json:"convertTarget,omitempty"`
+	EditInPlace   *bool   `json:"editInPlace,omitempty"`
+	RoundTrip     *bool   `json:"roundTrip,omitempty"`
+	FillForms     *bool   `json:"fillForms,omitempty"`
+	Macro         *bool   `json:"macro,omitempty"`
+	MIME          *string `json:"mime,omitempty"`
+	Disabled      bool    `json:"disabled,omitempty"` // Removes the format
+}
+
+// Overrides is the content of a formats file
+type Overrides struct {
+	Formats []Override `json:"formats"`
+}
+
+// Load creates a Manager with the built-in formats and applies the overrides
+// of a formats file. A missing file yields the built-in formats; an invalid
+// one yields the built-in formats and an error.
+func Load(path string) (*Manager, error) {
+	m := NewManager()
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return m, err
+	}
+	var overrides Overrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return m, fmt.Errorf("invalid formats %s: %w", path, err)
+	}
+
+	custom := NewManager()
+	if err := custom.Apply(overrides.Formats); err != nil {
+		return m, fmt.Errorf("invalid formats %s: %w", path, err)
+	}
+	return custom, nil
+}
+
+// Apply applies overrides to the format table and validates the result
+func (m *Manager) Apply(overrides []Override) error {
+	for i, o := range overrides {
+		ext := strings.ToLower(strings.TrimPrefix(o.Extension, "."))
+		if ext == "" {
+			return fmt.Errorf("format %d: extension is required", i+1)
+		}
+		if o.Disabled {
+			delete(m.formats, ext)
+			continue
+		}
+
+		f := &Format{Extension: ext}
+		if existing, ok := m.formats[ext]; ok {
+			copied := *existing
+			f = &copied
+		}
+		if o.Editable != nil && *o.Editable || o.ViewOnly != nil && *o.ViewOnly || o.Convertible != nil && *o.Convertible {
+			f.Editable, f.ViewOnly, f.Convertible = false, false, false
+		}
+		setBool(&f.Editable, o.Editable)
+		setBool(&f.ViewOnly, o.ViewOnly)
+		setBool(&f.Convertible, o.Convertible)
+		setBool(&f.EditInPlace, o.EditInPlace)
+		setBool(&f.RoundTrip, o.RoundTrip)
+		setBool(&f.FillForms, o.FillForms)
+		setBool(&f.Macro, o.Macro)
+		setString(&f.Type, o.Type)
+		setString(&f.ConvertTarget, o.ConvertTarget)
+		setString(&f.MIME, o.MIME)
+		if !f.Convertible {
+			f.ConvertTarget = ""
+			f.EditInPlace = false
+			f.RoundTrip = false
+		}
+		m.formats[ext] = f
+	}
+	return m.validate()
+}
+
+// validate checks that every format has a document type, exactly one way to
+// open it and an editable conversion target of the same type
+func (m *Manager) validate() error {
+	for _, f := range m.All() {
+		switch f.Type {
+		case "word", "cell", "slide":
+		default:
+			return fmt.Errorf("format %s: unknown type %q", f.Extension, f.Type)
+		}
+
+		n := 0
+		for _, set := range []bool{f.Editable, f.ViewOnly, f.Convertible} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Errorf("format %s: exactly one of editable, viewOnly and convertible must be set", f.Extension)
+		}
+
+		if f.Convertible {
+			target, ok := m.formats[f.ConvertTarget]
+			if !ok || !target.Editable || target.Type != f.Type {
+				return fmt.Errorf("format %s: convert target %q is not an editable %s format", f.Extension, f.ConvertTarget, f.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// All returns all formats sorted by extension
+func (m *Manager) All() []*Format {
+	result := make([]*Format, 0, len(m.formats))
+	for _, f := range m.formats {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Extension < result[j].Extension
+	})
+	return result
+}
+
+// Extensions returns the extensions of all formats, sorted
+func (m *Manager) Extensions() []string {
+	var result []string
+	for _, f := range m.All() {
+		result = append(result, f.Extension)
+	}
+	return result
+}
+
+func setBool(dst *bool, src *bool) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setString(dst *string, src *string) {
+	if src != nil {
+		*dst = *src
+	}
+}
@@ -0,0 +1,83 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Unit test: The built-in format table is valid
+func TestBuiltinFormatsValid(t *testing.T) {
+	if err := NewManager().validate(); err != nil {
+		t.Fatalf("built-in formats should be valid: %v", err)
+	}
+}
+
+// Unit test: Overrides switch ODF to native editing, force view-only and add formats
+func TestLoadOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "formats.json")
+	os.WriteFile(path, []byte(`{"formats": [
+		{"extension": "odt", "editable": true},
+		{"extension": "ods", "editInPlace": true},
+		{"extension": "pptm", "viewOnly": true},
+		{"extension": ".CSV", "convertTarget": "xlsm"},
+		{"extension": "org", "type": "word", "convertible": true, "convertTarget": "docx", "mime": "text/x-org"},
+		{"extension": "fb2", "disabled": true}
+	]}`), 0644)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !m.IsEditable("odt") || m.IsConvertible("odt") || m.GetDocumentType("odt") != "word" {
+		t.Errorf("odt should be editable, got %+v", m.formats["odt"])
+	}
+	if !m.IsEditableInPlace("ods") || m.GetConvertTarget("ods") != "xlsx" || m.IsEditableInPlace("odp") {
+		t.Errorf("only ods should be edited in place, got %+v", m.formats["ods"])
+	}
+	if !m.IsViewOnly("pptm") || m.IsEditable("pptm") || !m.IsMacroEnabled("pptm") {
+		t.Errorf("pptm should be view-only, got %+v", m.formats["pptm"])
+	}
+	if m.GetConvertTarget("csv") != "xlsm" {
+		t.Errorf("csv should convert to xlsm, got %s", m.GetConvertTarget("csv"))
+	}
+	if m.GetConvertTarget("org") != "docx" || m.GetMIMEType("org") != "text/x-org" {
+		t.Errorf("org should be added, got %+v", m.formats["org"])
+	}
+	if _, ok := m.GetFormat("fb2"); ok {
+		t.Error("fb2 should be removed")
+	}
+	if NewManager().IsEditable("odt") {
+		t.Error("overrides should not change the built-in table")
+	}
+}
+
+// Unit test: Invalid overrides are rejected and the built-in formats are kept
+func TestLoadInvalidOverrides(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"missing type":   `{"formats": [{"extension": "org", "viewOnly": true}]}`,
+		"bad target":     `{"formats": [{"extension": "odt", "convertTarget": "xlsx"}]}`,
+		"no open mode":   `{"formats": [{"extension": "pdf", "viewOnly": false}]}`,
+		"no extension":   `{"formats": [{"type": "word", "editable": true}]}`,
+		"removed target": `{"formats": [{"extension": "docx", "disabled": true}]}`,
+	}
+
+	for name, content := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".json")
+		os.WriteFile(path, []byte(content), 0644)
+
+		m, err := Load(path)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if m == nil || !m.IsEditable("docx") || !m.IsConvertible("odt") {
+			t.Errorf("%s: expected the built-in formats", name)
+		}
+	}
+
+	if m, err := Load(filepath.Join(dir, "missing.json")); err != nil || !m.IsEditable("docx") {
+		t.Errorf("missing file should yield the built-in formats, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// sniffLen is the number of leading bytes inspected for signatures and text
+const sniffLen = 4096
+
+// odfTypes maps the mimetype entry of ODF and other zip packages to extensions
+var odfTypes = []struct {
+	mime       string
+	extensions []string
+}{
+	{"application/vnd.oasis.opendocument.text-template", []string{"ott"}},
+	{"application/vnd.oasis.opendocument.text", []string{"odt"}},
+	{"application/vnd.oasis.opendocument.spreadsheet-template", []string{"ots"}},
+	{"application/vnd.oasis.opendocument.spreadsheet", []string{"ods"}},
+	{"application/vnd.oasis.opendocument.presentation-template", []string{"otp"}},
+	{"application/vnd.oasis.opendocument.presentation", []string{"odp"}},
+	{"application/epub+zip", []string{"epub"}},
+	{"application/hwp+zip", []string{"hwpx"}},
+}
+
+// ooxmlTypes maps the main part content type of OOXML packages to extensions
+var ooxmlTypes = []struct {
+	contentType string
+	extensions  []string
+}{
+	{"application/vnd.ms-word.document.macroEnabled.main+xml", []string{"docm"}},
+	{"application/vnd.ms-word.template.macroEnabledTemplate.main+xml", []string{"dotm"}},
+	{"application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml", []string{"dotx"}},
+	{"application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml", []string{"docx", "docxf", "oform"}},
+	{"application/vnd.ms-excel.sheet.macroEnabled.main+xml", []string{"xlsm"}},
+	{"application/vnd.ms-excel.template.macroEnabled.main+xml", []string{"xltm"}},
+	{"application/vnd.ms-excel.sheet.binary.macroEnabled.main", []string{"xlsb"}},
+	{"application/vnd.openxmlformats-officedocument.spreadsheetml.template.main+xml", []string{"xltx"}},
+	{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml", []string{"xlsx"}},
+	{"application/vnd.ms-powerpoint.presentation.macroEnabled.main+xml", []string{"pptm"}},
+	{"application/vnd.ms-powerpoint.slideshow.macroEnabled.main+xml", []string{"ppsm"}},
+	{"application/vnd.ms-powerpoint.template.macroEnabled.main+xml", []string{"potm"}},
+	{"application/vnd.openxmlformats-officedocument.presentationml.slideshow.main+xml", []string{"ppsx"}},
+	{"application/vnd.openxmlformats-officedocument.presentationml.template.main+xml", []string{"potx"}},
+	{"application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml", []string{"pptx"}},
+	{"application/vnd.ms-package.xps-fixeddocumentsequence+xml", []string{"xps", "oxps"}},
+	{"application/vnd.ms-package.xps-fixeddocumentsequence", []string{"oxps", "xps"}},
+}
+
+// cfbStreams maps a characteristic stream of legacy Office files to extensions
+var cfbStreams = []struct {
+	name       string
+	extensions []string
+}{
+	{"WordDocument", []string{"doc", "dot", "wps", "wpt"}},
+	{"Workbook", []string{"xls", "xlt", "et", "ett"}},
+	{"Book", []string{"xls", "xlt"}},
+	{"PowerPoint Document", []string{"ppt", "pps", "pot", "dps", "dpt"}},
+	{"FileHeader", []string{"hwp"}},
+}
+
+// Sniff detects the format of a file from its content. It returns the
+// extensions matching the content, the most likely first, or nil if the
+// content is not recognized.
+func Sniff(r io.ReaderAt, size int64) []string {
+	head := make([]byte, sniffLen)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return []string{"pdf"}
+	case bytes.HasPrefix(head, []byte(`{\rtf`)):
+		return []string{"rtf"}
+	case bytes.HasPrefix(head, []byte("AT&TFORM")):
+		return []string{"djvu"}
+	case bytes.HasPrefix(head, cfbSignature):
+		return sniffCFB(r, size)
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return sniffZip(r, size)
+	}
+	return sniffText(head)
+}
+
+// sniffCFB detects legacy Office formats by their streams
+func sniffCFB(r io.ReaderAt, size int64) []string {
+	c, err := openCFB(r, size)
+	if err != nil {
+		return nil
+	}
+	for _, stream := range cfbStreams {
+		if c.has(stream.name) {
+			return stream.extensions
+		}
+	}
+	return nil
+}
+
+// sniffZip detects ODF packages by their mimetype entry and OOXML packages
+// by the content type of their main part
+func sniffZip(r io.ReaderAt, size int64) []string {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range archive.File {
+		switch entry.Name {
+		case "mimetype":
+			mime := strings.TrimSpace(readZipEntry(entry))
+			for _, t := range odfTypes {
+				if mime == t.mime {
+					return t.extensions
+				}
+			}
+		case "[Content_Types].xml":
+			types := readZipEntry(entry)
+			for _, t := range ooxmlTypes {
+				if strings.Contains(types, `"`+t.contentType+`"`) {
+					return t.extensions
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// readZipEntry reads a small zip entry, empty on error
+func readZipEntry(entry *zip.File) string {
+	rc, err := entry.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(io.LimitReader(rc, 1<<20))
+	return string(data)
+}
+
+// sniffText detects markup, and CSV when the lines have the same number of
+// separators
+func sniffText(head []byte) []string {
+	if len(head) == 0 || bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+	text := bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF"))
+	if !validUTF8Prefix(text) {
+		return nil
+	}
+
+	start := strings.ToLower(strings.TrimSpace(string(text)))
+	switch {
+	case strings.HasPrefix(start, "<!doctype html"), strings.HasPrefix(start, "<html"):
+		return []string{"html", "htm"}
+	case strings.HasPrefix(start, "<?xml"):
+		return sniffXML(start)
+	case strings.Contains(start, "content-type: multipart/related") && strings.Contains(strings.SplitN(start, "\n", 2)[0], ":"):
+		// A web archive starts with mail headers
+		return []string{"mht"}
+	}
+
+	// Any file without binary data passes as plain text, so only tabular
+	// text is recognized
+	if _, ok := csvSeparator(string(text)); ok {
+		return []string{"csv", "txt"}
+	}
+	return nil
+}
+
+// sniffXML detects flat ODF documents and FictionBook, other XML is not recognized
+func sniffXML(start string) []string {
+	for ext, kind := range map[string]string{"fodt": "text", "fods": "spreadsheet", "fodp": "presentation"} {
+		if strings.Contains(start, `office:mimetype="application/vnd.oasis.opendocument.`+kind+`"`) {
+			return []string{ext}
+		}
+	}
+	if strings.Contains(start, "<fictionbook") {
+		return []string{"fb2"}
+	}
+	return nil
+}
+
+// DetectType returns the type to open a file with. The extension is kept if
+// the content matches it or is not recognized; otherwise the first detected
+// format in the table is used.
+func (m *Manager) DetectType(extension string, detected []string) string {
+	ext := strings.ToLower(strings.TrimPrefix(extension, "."))
+	for _, candidate := range detected {
+		if candidate == ext {
+			return ext
+		}
+	}
+	for _, candidate := range detected {
+		if _, ok := m.formats[candidate]; ok {
+			return candidate
+		}
+	}
+	return ext
+}
@@ -0,0 +1,188 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// buildZip builds a zip package with the given entries
+func buildZip(t *testing.T, entries map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range entries {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+// cfbStream is a stream of a compound file built for tests
+type cfbStream struct {
+	name string
+	data []byte
+	mini bool // Stored in the mini stream instead of regular sectors
+}
+
+// buildCFB builds a compound file with one FAT sector, one directory sector
+// holding the root entry and up to three streams, and the stream sectors.
+// Stream data is padded to the mini stream cutoff, mini streams to 64 bytes.
+func buildCFB(streams ...cfbStream) []byte {
+	data := make([]byte, 512*3)
+	copy(data, cfbSignature)
+	binary.LittleEndian.PutUint16(data[0x1A:], 3)
+	binary.LittleEndian.PutUint16(data[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(data[0x1E:], 9)
+	binary.LittleEndian.PutUint16(data[0x20:], 6)
+	binary.LittleEndian.PutUint32(data[0x2C:], 1)          // FAT sectors
+	binary.LittleEndian.PutUint32(data[0x30:], 1)          // First directory sector
+	binary.LittleEndian.PutUint32(data[0x44:], 0xFFFFFFFE) // No DIFAT sectors
+	for i := 0; i < 109; i++ {
+		binary.LittleEndian.PutUint32(data[0x4C+4*i:], 0xFFFFFFFF)
+	}
+	binary.LittleEndian.PutUint32(data[0x4C:], 0)
+
+	fat := make([]uint32, 128)
+	for i := range fat {
+		fat[i] = 0xFFFFFFFF
+	}
+	fat[0] = 0xFFFFFFFD
+	fat[1] = 0xFFFFFFFE
+
+	writeEntry := func(i int, name string, kind byte, start uint32, size int) {
+		entry := data[512*2+128*i : 512*2+128*(i+1)]
+		encoded := utf16.Encode([]rune(name))
+		for j, c := range encoded {
+			binary.LittleEndian.PutUint16(entry[2*j:], c)
+		}
+		binary.LittleEndian.PutUint16(entry[0x40:], uint16(2*len(encoded)+2))
+		entry[0x42] = kind
+		binary.LittleEndian.PutUint32(entry[0x74:], start)
+		binary.LittleEndian.PutUint64(entry[0x78:], uint64(size))
+	}
+	writeEntry(0, "Root Entry", 5, 0xFFFFFFFE, 0)
+
+	var miniData []byte
+	var miniFAT []uint32
+	for i, stream := range streams {
+		if stream.mini {
+			first := uint32(len(miniData) / 64)
+			content := make([]byte, (len(stream.data)+63)/64*64)
+			copy(content, stream.data)
+			for offset := 64; offset <= len(content); offset += 64 {
+				miniFAT = append(miniFAT, uint32(len(miniData)+offset)/64)
+			}
+			miniFAT[len(miniFAT)-1] = 0xFFFFFFFE
+			miniData = append(miniData, content...)
+			writeEntry(i+1, stream.name, 2, first, len(stream.data))
+			continue
+		}
+		content := make([]byte, 4096)
+		copy(content, stream.data)
+		first := uint32(len(data)/512 - 1)
+		for offset := 0; offset < len(content); offset += 512 {
+			sector := uint32(len(data)/512 - 1)
+			fat[sector] = sector + 1
+			data = append(data, content[offset:offset+512]...)
+		}
+		fat[len(data)/512-2] = 0xFFFFFFFE
+		writeEntry(i+1, stream.name, 2, first, len(content))
+	}
+
+	if len(miniData) > 0 {
+		miniFATSector := uint32(len(data)/512 - 1)
+		sector := make([]byte, 512)
+		for i := range sector {
+			sector[i] = 0xFF
+		}
+		for i, next := range miniFAT {
+			binary.LittleEndian.PutUint32(sector[4*i:], next)
+		}
+		data = append(data, sector...)
+		fat[miniFATSector] = 0xFFFFFFFE
+		binary.LittleEndian.PutUint32(data[0x3C:], miniFATSector)
+		binary.LittleEndian.PutUint32(data[0x40:], 1)
+
+		rootStart := uint32(len(data)/512 - 1)
+		content := make([]byte, (len(miniData)+511)/512*512)
+		copy(content, miniData)
+		for offset := 0; offset < len(content); offset += 512 {
+			sector := uint32(len(data)/512 - 1)
+			fat[sector] = sector + 1
+			data = append(data, content[offset:offset+512]...)
+		}
+		fat[len(data)/512-2] = 0xFFFFFFFE
+		writeEntry(0, "Root Entry", 5, rootStart, len(miniData))
+	}
+
+	for i, next := range fat {
+		binary.LittleEndian.PutUint32(data[512+4*i:], next)
+	}
+	return data
+}
+
+// Unit test: Formats are detected from their signatures and containers
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		expected []string
+	}{
+		{"pdf", []byte("%PDF-1.7\n"), []string{"pdf"}},
+		{"rtf", []byte(`{\rtf1\ansi hello}`), []string{"rtf"}},
+		{"doc", buildCFB(cfbStream{name: "\x01CompObj"}, cfbStream{name: "WordDocument"}), []string{"doc", "dot", "wps", "wpt"}},
+		{"xls", buildCFB(cfbStream{name: "Workbook"}), []string{"xls", "xlt", "et", "ett"}},
+		{"ppt", buildCFB(cfbStream{name: "PowerPoint Document"}), []string{"ppt", "pps", "pot", "dps", "dpt"}},
+		{"unknown cfb", buildCFB(cfbStream{name: "Contents"}), nil},
+		{"docx", buildZip(t, map[string]string{"[Content_Types].xml": `<Override ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>`}), []string{"docx", "docxf", "oform"}},
+		{"xlsm", buildZip(t, map[string]string{"[Content_Types].xml": `<Override ContentType="application/vnd.ms-excel.sheet.macroEnabled.main+xml"/>`}), []string{"xlsm"}},
+		{"ods", buildZip(t, map[string]string{"mimetype": "application/vnd.oasis.opendocument.spreadsheet"}), []string{"ods"}},
+		{"plain zip", buildZip(t, map[string]string{"readme.txt": "hello"}), nil},
+		{"html", []byte("\n<!DOCTYPE html><html><table></table></html>"), []string{"html", "htm"}},
+		{"fodt", []byte(`<?xml version="1.0"?><office:document office:mimetype="application/vnd.oasis.opendocument.text">`), []string{"fodt"}},
+		{"mht", []byte("From: <Saved by Blink>\r\nMIME-Version: 1.0\r\nContent-Type: multipart/related;\r\n\ttype=\"text/html\"\r\n"), []string{"mht"}},
+		{"csv", []byte("\xEF\xBB\xBFname;amount\nalice;1\nbob;2\n"), []string{"csv", "txt"}},
+		{"plain text", []byte("just some notes\nwithout columns\n"), nil},
+		{"binary", []byte{0x00, 0x01, 0x02}, nil},
+	}
+
+	for _, tt := range tests {
+		actual := Sniff(bytes.NewReader(tt.content), int64(len(tt.content)))
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+// Unit test: The extension is kept unless the content contradicts it
+func TestDetectType(t *testing.T) {
+	m := NewManager()
+
+	tests := []struct {
+		ext      string
+		detected []string
+		expected string
+	}{
+		{"doc", []string{"rtf"}, "rtf"},
+		{"dot", []string{"doc", "dot"}, "dot"},
+		{"xls", []string{"csv", "txt"}, "csv"},
+		{"xls", []string{"html", "htm"}, "html"},
+		{"doc", []string{"pages"}, "pages"},
+		{"doc", []string{"unknown"}, "doc"}, // Not in the format table
+		{"docx", nil, "docx"},
+		{".DOCX", []string{"docx"}, "docx"},
+	}
+
+	for _, tt := range tests {
+		if actual := m.DetectType(tt.ext, tt.detected); actual != tt.expected {
+			t.Errorf("%s %v: expected %s, got %s", tt.ext, tt.detected, tt.expected, actual)
+		}
+	}
+}
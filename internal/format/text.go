@@ -0,0 +1,178 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Code pages understood by the conversion API for csv and txt files
+const (
+	CodePageUTF8    = 65001
+	CodePageGBK     = 936
+	CodePageBig5    = 950
+	CodePageSJIS    = 932
+	CodePageEUCKR   = 949
+	CodePageWestern = 1252
+	CodePageUTF16LE = 1200
+	CodePageUTF16BE = 1201
+)
+
+// Delimiters understood by the conversion API for csv files
+const (
+	DelimiterTab       = 1
+	DelimiterSemicolon = 2
+	DelimiterColon     = 3
+	DelimiterComma     = 4
+	DelimiterSpace     = 5
+)
+
+// Encoding is a text encoding offered for csv and txt files
+type Encoding struct {
+	CodePage int    `json:"codePage"`
+	Name     string `json:"name"`
+	Label    string `json:"label"` // WHATWG encoding label, for previews in the browser
+}
+
+// Encodings lists the supported text encodings
+var Encodings = []Encoding{
+	{CodePageUTF8, "UTF-8", "utf-8"},
+	{CodePageGBK, "GBK (简体中文)", "gbk"},
+	{CodePageBig5, "Big5 (繁體中文)", "big5"},
+	{CodePageSJIS, "Shift_JIS (日本語)", "shift_jis"},
+	{CodePageEUCKR, "EUC-KR (한국어)", "euc-kr"},
+	{CodePageWestern, "Windows-1252 (Western)", "windows-1252"},
+	{CodePageUTF16LE, "UTF-16 LE", "utf-16le"},
+	{CodePageUTF16BE, "UTF-16 BE", "utf-16be"},
+}
+
+// Delimiter is a column separator offered for csv files
+type Delimiter struct {
+	Value int    `json:"value"`
+	Name  string `json:"name"`
+	Char  string `json:"char"`
+}
+
+// Delimiters lists the supported column separators
+var Delimiters = []Delimiter{
+	{DelimiterComma, "逗号", ","},
+	{DelimiterSemicolon, "分号", ";"},
+	{DelimiterTab, "制表符", "\t"},
+	{DelimiterColon, "冒号", ":"},
+	{DelimiterSpace, "空格", " "},
+}
+
+// ValidCodePage returns true if the code page is in Encodings
+func ValidCodePage(codePage int) bool {
+	for _, e := range Encodings {
+		if e.CodePage == codePage {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidDelimiter returns true if the delimiter is in Delimiters
+func ValidDelimiter(delimiter int) bool {
+	for _, d := range Delimiters {
+		if d.Value == delimiter {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectCodePage guesses the encoding of a text sample: byte order marks,
+// then UTF-8, then GBK, which most non-UTF-8 files on Chinese systems use
+func DetectCodePage(sample []byte) int {
+	switch {
+	case bytes.HasPrefix(sample, []byte("\xEF\xBB\xBF")):
+		return CodePageUTF8
+	case bytes.HasPrefix(sample, []byte("\xFF\xFE")):
+		return CodePageUTF16LE
+	case bytes.HasPrefix(sample, []byte("\xFE\xFF")):
+		return CodePageUTF16BE
+	case validUTF8Prefix(sample):
+		return CodePageUTF8
+	case validGBK(sample):
+		return CodePageGBK
+	}
+	return CodePageWestern
+}
+
+// DetectDelimiter guesses the column separator of a csv sample from the
+// separators that occur the same number of times in the first lines
+func DetectDelimiter(sample []byte) int {
+	if sep, ok := csvSeparator(string(sample)); ok {
+		for _, d := range Delimiters {
+			if d.Char == sep {
+				return d.Value
+			}
+		}
+	}
+	return DelimiterComma
+}
+
+// csvSeparator returns the separator occurring equally often in each of the
+// first lines, ignoring the last line which may be cut off
+func csvSeparator(text string) (string, bool) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) < 2 {
+		return "", false
+	}
+	for _, sep := range []string{",", ";", "\t"} {
+		count := strings.Count(lines[0], sep)
+		if count == 0 {
+			continue
+		}
+		same := true
+		for _, line := range lines[1:] {
+			if line != "" && strings.Count(line, sep) != count {
+				same = false
+				break
+			}
+		}
+		if same {
+			return sep, true
+		}
+	}
+	return "", false
+}
+
+// validUTF8Prefix returns true if the sample is UTF-8, allowing a character
+// cut off at the end
+func validUTF8Prefix(sample []byte) bool {
+	for len(sample) > 0 {
+		r, size := utf8.DecodeRune(sample)
+		if r == utf8.RuneError && size == 1 {
+			return !utf8.FullRune(sample)
+		}
+		sample = sample[size:]
+	}
+	return true
+}
+
+// validGBK returns true if the sample consists of ASCII and GBK double-byte
+// characters, allowing a character cut off at the end
+func validGBK(sample []byte) bool {
+	for i := 0; i < len(sample); i++ {
+		b := sample[i]
+		if b < 0x80 {
+			continue
+		}
+		if b == 0x80 || b == 0xFF {
+			return false
+		}
+		if i+1 == len(sample) {
+			return true
+		}
+		if trail := sample[i+1]; trail < 0x40 || trail == 0x7F || trail == 0xFF {
+			return false
+		}
+		i++
+	}
+	return true
+}
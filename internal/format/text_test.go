@@ -0,0 +1,46 @@
+package format
+
+import "testing"
+
+// Unit test: encodings are detected from byte order marks, UTF-8 and GBK
+func TestDetectCodePage(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample string
+		want   int
+	}{
+		{"ascii", "a,b\n1,2\n", CodePageUTF8},
+		{"utf-8", "姓名,年龄\n", CodePageUTF8},
+		{"utf-8 cut off", "姓名,年龄\n张"[:len("姓名,年龄\n张")-1], CodePageUTF8},
+		{"utf-8 bom", "\xEF\xBB\xBFa,b\n", CodePageUTF8},
+		{"utf-16le bom", "\xFF\xFEa\x00", CodePageUTF16LE},
+		{"utf-16be bom", "\xFE\xFF\x00a", CodePageUTF16BE},
+		{"gbk", "\xD0\xD5\xC3\xFB,\xC4\xEA\xC1\xE4\n", CodePageGBK},
+		{"gbk cut off", "\xD0\xD5\xC3", CodePageGBK},
+		{"latin-1", "caf\xE9,na\xEFve\n", CodePageWestern},
+	}
+	for _, tt := range tests {
+		if got := DetectCodePage([]byte(tt.sample)); got != tt.want {
+			t.Errorf("%s: DetectCodePage() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Unit test: delimiters are detected from consistent counts, comma by default
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		sample string
+		want   int
+	}{
+		{"a,b,c\n1,2,3\n", DelimiterComma},
+		{"a;b;c\n1,5;2;3\n", DelimiterSemicolon},
+		{"a\tb\n1\t2\n3\t4", DelimiterTab},
+		{"just one line\n", DelimiterComma},
+		{"", DelimiterComma},
+	}
+	for _, tt := range tests {
+		if got := DetectDelimiter([]byte(tt.sample)); got != tt.want {
+			t.Errorf("DetectDelimiter(%q) = %d, want %d", tt.sample, got, tt.want)
+		}
+	}
+}
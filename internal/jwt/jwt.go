@@ -2,8 +2,10 @@ package jwt
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -23,26 +25,32 @@ func NewManager() *Manager {
 	return &Manager{}
 }
 
-// Sign creates a JWT token with the given claims
-func (m *Manager) Sign(secret string, claims map[string]interface{}) (string, error) {
-	jwtClaims := jwt.MapClaims{}
-	for k, v := range claims {
-		jwtClaims[k] = v
-	}
+// Sign creates a JWT token with the given claims. An optional key ID is
+// sent as the "kid" header.
+func (m *Manager) Sign(secret string, claims map[string]interface{}, kid ...string) (string, error) {
+	return m.sign(secret, claims, 0, kid)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims)
-	return token.SignedString([]byte(secret))
+// SignWithExpiry creates a JWT token with expiration time. An optional key
+// ID is sent as the "kid" header.
+func (m *Manager) SignWithExpiry(secret string, claims map[string]interface{}, expiry time.Duration, kid ...string) (string, error) {
+	return m.sign(secret, claims, expiry, kid)
 }
 
-// SignWithExpiry creates a JWT token with expiration time
-func (m *Manager) SignWithExpiry(secret string, claims map[string]interface{}, expiry time.Duration) (string, error) {
+// sign creates a JWT token, without expiration if expiry is zero
+func (m *Manager) sign(secret string, claims map[string]interface{}, expiry time.Duration, kid []string) (string, error) {
 	jwtClaims := jwt.MapClaims{}
 	for k, v := range claims {
 		jwtClaims[k] = v
 	}
-	jwtClaims["exp"] = time.Now().Add(expiry).Unix()
+	if expiry != 0 {
+		jwtClaims["exp"] = time.Now().Add(expiry).Unix()
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims)
+	if len(kid) > 0 && kid[0] != "" {
+		token.Header["kid"] = kid[0]
+	}
 	return token.SignedString([]byte(secret))
 }
 
@@ -85,3 +93,98 @@ func (m *Manager) GenerateSecret() string {
 	}
 	return hex.EncodeToString(bytes)
 }
+
+// Key is a signing secret with an optional key ID, sent as the "kid" header
+type Key struct {
+	ID     string
+	Secret string
+}
+
+// Keyring holds the primary signing key and additional keys that are still
+// accepted for verification, allowing secrets to be rotated without downtime
+type Keyring struct {
+	Primary  Key
+	Accepted []Key
+}
+
+// NewKeyring creates a Keyring from a primary secret and a list of accepted secrets.
+// Accepted entries may be given as "kid:secret" to assign a key ID.
+func NewKeyring(primaryID, primary string, accepted []string) *Keyring {
+	kr := &Keyring{Primary: Key{ID: primaryID, Secret: primary}}
+	for _, entry := range accepted {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key := Key{Secret: entry}
+		if idx := strings.Index(entry, ":"); idx > 0 {
+			key = Key{ID: entry[:idx], Secret: entry[idx+1:]}
+		}
+		kr.Accepted = append(kr.Accepted, key)
+	}
+	return kr
+}
+
+// Enabled returns true if a primary secret is configured
+func (kr *Keyring) Enabled() bool {
+	return kr != nil && kr.Primary.Secret != ""
+}
+
+// Keys returns the primary key followed by all accepted keys
+func (kr *Keyring) Keys() []Key {
+	if kr == nil {
+		return nil
+	}
+	keys := make([]Key, 0, len(kr.Accepted)+1)
+	if kr.Primary.Secret != "" {
+		keys = append(keys, kr.Primary)
+	}
+	return append(keys, kr.Accepted...)
+}
+
+// Fingerprint returns a short non-reversible identifier for a secret, for logs
+func (k Key) Fingerprint() string {
+	sum := sha256.Sum256([]byte(k.Secret))
+	return hex.EncodeToString(sum[:4])
+}
+
+// VerifyWithKeys validates a JWT token against a set of keys. If the token
+// names a known key ID only that key is tried, otherwise every key is tried.
+func (m *Manager) VerifyWithKeys(keys []Key, tokenString string) (map[string]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	candidates := keys
+	if kid := tokenKeyID(tokenString); kid != "" {
+		for _, key := range keys {
+			if key.ID == kid {
+				candidates = []Key{key}
+				break
+			}
+		}
+	}
+
+	err := ErrInvalidToken
+	for _, key := range candidates {
+		claims, verr := m.Verify(key.Secret, tokenString)
+		if verr == nil {
+			return claims, nil
+		}
+		// A matching signature with an expired token is the more useful error
+		if verr == ErrExpiredToken {
+			err = verr
+		}
+	}
+	return nil, err
+}
+
+// tokenKeyID returns the unverified "kid" header of a token
+func tokenKeyID(tokenString string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
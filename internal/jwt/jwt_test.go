@@ -148,3 +148,67 @@ func TestGenerateSecretFormat(t *testing.T) {
 		}
 	}
 }
+
+// Unit test: Tokens signed with the primary or an accepted key verify against the keyring
+func TestKeyringRotation(t *testing.T) {
+	m := NewManager()
+	oldSecret := m.GenerateSecret()
+	newSecret := m.GenerateSecret()
+
+	kr := NewKeyring("v2", newSecret, []string{"v1:" + oldSecret})
+	if kr.Accepted[0].ID != "v1" || kr.Accepted[0].Secret != oldSecret {
+		t.Fatalf("accepted key not parsed: %+v", kr.Accepted[0])
+	}
+
+	claims := map[string]interface{}{"key": "value"}
+
+	// Token from a Document Server still using the old secret (no kid)
+	oldToken, _ := m.Sign(oldSecret, claims)
+	if _, err := m.VerifyWithKeys(kr.Keys(), oldToken); err != nil {
+		t.Fatalf("token signed with accepted secret should verify: %v", err)
+	}
+
+	// Token signed by the connector carries the primary kid
+	newToken, _ := m.Sign(kr.Primary.Secret, claims, kr.Primary.ID)
+	if kid := tokenKeyID(newToken); kid != "v2" {
+		t.Fatalf("expected kid v2, got %q", kid)
+	}
+	if _, err := m.VerifyWithKeys(kr.Keys(), newToken); err != nil {
+		t.Fatalf("token signed with primary secret should verify: %v", err)
+	}
+
+	// Once the old secret is removed its tokens are rejected
+	retired := NewKeyring("v2", newSecret, nil)
+	if _, err := m.VerifyWithKeys(retired.Keys(), oldToken); err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for retired secret, got %v", err)
+	}
+
+	// A kid pointing at the wrong key must not fall back to other keys
+	forged, _ := m.Sign(newSecret, claims, "v1")
+	if _, err := m.VerifyWithKeys(kr.Keys(), forged); err == nil {
+		t.Fatal("token with mismatching kid should fail")
+	}
+}
+
+// Unit test: SignWithExpiry sets an expiry and a kid that VerifyWithKeys enforces
+func TestSignWithExpiryKeyID(t *testing.T) {
+	m := NewManager()
+	key := Key{ID: "v1", Secret: m.GenerateSecret()}
+
+	expired, _ := m.SignWithExpiry(key.Secret, map[string]interface{}{"a": 1}, -time.Hour, key.ID)
+	if kid := tokenKeyID(expired); kid != "v1" {
+		t.Fatalf("expected kid v1, got %q", kid)
+	}
+	if _, err := m.VerifyWithKeys([]Key{key}, expired); err != ErrExpiredToken {
+		t.Fatalf("expected ErrExpiredToken, got %v", err)
+	}
+
+	valid, _ := m.SignWithExpiry(key.Secret, map[string]interface{}{"a": 1}, time.Hour, key.ID)
+	claims, err := m.VerifyWithKeys([]Key{key}, valid)
+	if err != nil {
+		t.Fatalf("token should verify: %v", err)
+	}
+	if _, ok := claims["exp"]; !ok {
+		t.Error("token should carry an exp claim")
+	}
+}
@@ -0,0 +1,220 @@
+package lru
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache keeps files by key, evicting the least recently used ones above a
+// size limit. On disk the order of use is kept in the modification times of
+// the files so it survives a restart.
+type Cache struct {
+	mu       sync.Mutex
+	dir      string // Files are kept in memory if empty
+	maxBytes int64
+	size     int64
+	lru      *list.List // Most recently used first
+	entries  map[string]*list.Element
+}
+
+// entry is a cached file
+type entry struct {
+	key  string
+	size int64
+	data []byte // Only kept in memory
+}
+
+// New creates a Cache holding up to maxBytes. If dir is not empty, files are
+// stored in it; otherwise they are kept in memory. A limit of 0 disables
+// caching.
+func New(dir string, maxBytes int64) *Cache {
+	c := &Cache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	if dir == "" || maxBytes <= 0 {
+		return c
+	}
+	c.dir = dir
+
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Cache: failed to read %s: %v", c.dir, err)
+		}
+		return c
+	}
+	var infos []os.FileInfo
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if filepath.Ext(info.Name()) == ".tmp" {
+			// Left over from an interrupted write
+			os.Remove(filepath.Join(c.dir, info.Name()))
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		c.entries[info.Name()] = c.lru.PushFront(&entry{key: info.Name(), size: info.Size()})
+		c.size += info.Size()
+	}
+	c.evict()
+	return c
+}
+
+// Enabled returns true if files are cached
+func (c *Cache) Enabled() bool {
+	return c != nil && c.maxBytes > 0
+}
+
+// Open returns a cached file and marks it as recently used
+func (c *Cache) Open(key string) (io.ReadCloser, bool) {
+	if !c.Enabled() {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.dir == "" {
+		c.lru.MoveToFront(elem)
+		return io.NopCloser(bytes.NewReader(elem.Value.(*entry).data)), true
+	}
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	now := time.Now()
+	os.Chtimes(c.path(key), now, now)
+	return f, true
+}
+
+// Has returns true if a file is cached
+func (c *Cache) Has(key string) bool {
+	if !c.Enabled() {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[key]
+	return ok
+}
+
+// Store caches a file and returns the cached copy for reading. It takes
+// ownership of content; if caching is disabled or the cache folder cannot be
+// written to, content is returned. An error means content was consumed.
+func (c *Cache) Store(key string, content io.ReadCloser) (io.ReadCloser, error) {
+	if !c.Enabled() {
+		return content, nil
+	}
+	if c.dir == "" {
+		defer content.Close()
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return nil, err
+		}
+		c.add(&entry{key: key, size: int64(len(data)), data: data})
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		log.Printf("Cache: failed to create %s: %v", c.dir, err)
+		return content, nil
+	}
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		log.Printf("Cache: failed to store %s: %v", key, err)
+		return content, nil
+	}
+	defer content.Close()
+
+	size, err := io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	// Open before evicting, a file larger than the limit is not kept but can
+	// still be read
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+	c.add(&entry{key: key, size: size})
+	return f, nil
+}
+
+// Size returns the total size of the cached files
+func (c *Cache) Size() int64 {
+	if !c.Enabled() {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// add records a stored file as the most recently used one
+func (c *Cache) add(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[e.key]; ok {
+		c.size -= elem.Value.(*entry).size
+		c.lru.Remove(elem)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += e.size
+	c.evict()
+}
+
+// evict removes the least recently used files above the size limit, the
+// caller holds the lock
+func (c *Cache) evict() {
+	for c.size > c.maxBytes && c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove deletes a file, the caller holds the lock
+func (c *Cache) remove(elem *list.Element) {
+	e := elem.Value.(*entry)
+	c.lru.Remove(elem)
+	delete(c.entries, e.key)
+	c.size -= e.size
+	if c.dir == "" {
+		return
+	}
+	if err := os.Remove(c.path(e.key)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Cache: failed to remove %s: %v", e.key, err)
+	}
+}
+
+// path returns the file of a key
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
@@ -0,0 +1,100 @@
+package lru
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Unit test: files are evicted least recently used first and the order survives a restart
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, 10)
+
+	store := func(c *Cache, key, content string) {
+		rc, err := c.Store(key, io.NopCloser(strings.NewReader(content)))
+		if err != nil {
+			t.Fatalf("Store(%s) failed: %v", key, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(data) != content {
+			t.Errorf("Store(%s) returned %q", key, data)
+		}
+	}
+	cached := func(c *Cache, key string) string {
+		rc, ok := c.Open(key)
+		if !ok {
+			return ""
+		}
+		defer rc.Close()
+		data, _ := io.ReadAll(rc)
+		return string(data)
+	}
+
+	store(c, "a", "aaaa")
+	store(c, "b", "bbbb")
+	time.Sleep(10 * time.Millisecond)
+	if cached(c, "a") != "aaaa" {
+		t.Fatal("expected a to be cached")
+	}
+	store(c, "c", "cccc")
+	if cached(c, "b") != "" || cached(c, "a") != "aaaa" || cached(c, "c") != "cccc" {
+		t.Error("expected the least recently used file to be evicted")
+	}
+	if c.Size() != 8 {
+		t.Errorf("expected 8 bytes cached, got %d", c.Size())
+	}
+
+	// A file larger than the limit is returned but not kept
+	store(c, "big", "0123456789abc")
+	if cached(c, "big") != "" || c.Size() != 0 {
+		t.Errorf("expected the large file not to be kept, %d bytes cached", c.Size())
+	}
+
+	store(c, "d", "dddd")
+	time.Sleep(10 * time.Millisecond)
+	store(c, "e", "eeee")
+	os.WriteFile(filepath.Join(dir, "f-123.tmp"), []byte("partial"), 0644)
+
+	reloaded := New(dir, 10)
+	if reloaded.Size() != 8 || cached(reloaded, "d") != "dddd" {
+		t.Fatalf("expected the files to survive a restart, %d bytes cached", reloaded.Size())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "f-123.tmp")); !os.IsNotExist(err) {
+		t.Error("expected partial files to be removed")
+	}
+	store(reloaded, "g", "gggg")
+	if cached(reloaded, "e") != "" || cached(reloaded, "d") != "dddd" {
+		t.Error("expected the order of use to survive a restart")
+	}
+}
+
+// Unit test: without a folder files are kept in memory
+func TestMemoryCache(t *testing.T) {
+	c := New("", 10)
+	for _, key := range []string{"a", "b", "a", "c"} {
+		if rc, ok := c.Open(key); ok {
+			rc.Close()
+			continue
+		}
+		rc, err := c.Store(key, io.NopCloser(strings.NewReader(strings.Repeat(key, 4))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc.Close()
+	}
+	if !c.Has("a") || c.Has("b") || !c.Has("c") || c.Size() != 8 {
+		t.Errorf("expected b to be evicted, got a %v b %v c %v with %d bytes", c.Has("a"), c.Has("b"), c.Has("c"), c.Size())
+	}
+	rc, ok := c.Open("c")
+	if !ok {
+		t.Fatal("expected c to be cached")
+	}
+	if data, _ := io.ReadAll(rc); string(data) != "cccc" {
+		t.Errorf("expected the cached content, got %q", data)
+	}
+}
@@ -0,0 +1,192 @@
+package newdoc
+
+import (
+	"archive/zip"
+	"bytes"
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnknownKind = errors.New("unknown document kind")
+)
+
+// Document kinds that can be created
+const (
+	KindWord  = "word"
+	KindCell  = "cell"
+	KindSlide = "slide"
+	KindForm  = "form"
+)
+
+//go:embed all:blank
+var blankFS embed.FS
+
+// Kind describes a kind of document that can be created
+type Kind struct {
+	Name        string // word, cell, slide, form
+	Extension   string // Extension of created files
+	DefaultName string // Default file name without extension
+	blank       string // Directory below blank/ holding the package parts
+}
+
+// kinds lists all creatable document kinds
+var kinds = []*Kind{
+	{Name: KindWord, Extension: "docx", DefaultName: "新建文档", blank: "docx"},
+	{Name: KindCell, Extension: "xlsx", DefaultName: "新建表格", blank: "xlsx"},
+	{Name: KindSlide, Extension: "pptx", DefaultName: "新建演示文稿", blank: "pptx"},
+	{Name: KindForm, Extension: "docxf", DefaultName: "新建表单", blank: "docx"},
+}
+
+// templateKinds maps template file extensions to the kind of document they create.
+// Templates are copied as-is, so only document (not .dotx style) packages are used.
+var templateKinds = map[string]string{
+	"docx":  KindWord,
+	"xlsx":  KindCell,
+	"pptx":  KindSlide,
+	"docxf": KindForm,
+}
+
+// Kinds returns all creatable document kinds
+func Kinds() []*Kind {
+	return kinds
+}
+
+// GetKind returns the kind with the given name
+func GetKind(name string) (*Kind, error) {
+	for _, k := range kinds {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return nil, ErrUnknownKind
+}
+
+// KindForDocumentType returns the kind of new documents for an editor document type
+func KindForDocumentType(documentType string) string {
+	switch documentType {
+	case "cell":
+		return KindCell
+	case "slide":
+		return KindSlide
+	default:
+		return KindWord
+	}
+}
+
+// Blank returns a minimal valid OOXML package for the kind
+func (k *Kind) Blank() ([]byte, error) {
+	root := path.Join("blank", k.blank)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	// [Content_Types].xml must be the first entry of the package
+	names := []string{"[Content_Types].xml"}
+	err := fs.WalkDir(blankFS, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(p, root+"/")
+		if !d.IsDir() && rel != names[0] {
+			names = append(names, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		data, err := blankFS.ReadFile(path.Join(root, name))
+		if err != nil {
+			return nil, err
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Template is a user-provided template file
+type Template struct {
+	Name string // Display name (file name without extension)
+	Path string // Absolute path of the template file
+	Kind string
+}
+
+// ListTemplates returns the templates in dir, optionally filtered by kind.
+// A missing directory yields no templates.
+func ListTemplates(dir, kind string) ([]*Template, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result []*Template
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(entry.Name()), "."))
+		tk, ok := templateKinds[ext]
+		if !ok || (kind != "" && tk != kind) {
+			continue
+		}
+		result = append(result, &Template{
+			Name: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+			Path: filepath.Join(dir, entry.Name()),
+			Kind: tk,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// OpenTemplate opens a template file, which must be located directly in dir
+func OpenTemplate(dir, templatePath string) (io.ReadCloser, *Template, error) {
+	if dir == "" || filepath.Dir(filepath.Clean(templatePath)) != filepath.Clean(dir) {
+		return nil, nil, os.ErrNotExist
+	}
+
+	templates, err := ListTemplates(dir, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, t := range templates {
+		if t.Path == filepath.Clean(templatePath) {
+			f, err := os.Open(t.Path)
+			if err != nil {
+				return nil, nil, err
+			}
+			return f, t, nil
+		}
+	}
+	return nil, nil, os.ErrNotExist
+}
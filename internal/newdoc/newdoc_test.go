@@ -0,0 +1,79 @@
+package newdoc
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Unit test: Blank packages are valid zips with the expected main part
+func TestBlankPackages(t *testing.T) {
+	mainParts := map[string]string{
+		KindWord:  "word/document.xml",
+		KindCell:  "xl/workbook.xml",
+		KindSlide: "ppt/presentation.xml",
+		KindForm:  "word/document.xml",
+	}
+
+	for _, k := range Kinds() {
+		data, err := k.Blank()
+		if err != nil {
+			t.Fatalf("%s: failed to build blank package: %v", k.Name, err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s: blank package is not a valid zip: %v", k.Name, err)
+		}
+
+		if zr.File[0].Name != "[Content_Types].xml" {
+			t.Errorf("%s: first entry should be [Content_Types].xml, got %s", k.Name, zr.File[0].Name)
+		}
+
+		found := map[string]bool{}
+		for _, f := range zr.File {
+			found[f.Name] = true
+		}
+		for _, name := range []string{"_rels/.rels", mainParts[k.Name]} {
+			if !found[name] {
+				t.Errorf("%s: missing part %s", k.Name, name)
+			}
+		}
+	}
+}
+
+// Unit test: Templates are listed by kind and only opened from the template directory
+func TestTemplates(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Report.docx", "Budget.xlsx", "notes.txt", ".hidden.docx"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644)
+	}
+
+	all, err := ListTemplates(dir, "")
+	if err != nil {
+		t.Fatalf("failed to list templates: %v", err)
+	}
+	if len(all) != 2 || all[0].Name != "Budget" || all[1].Name != "Report" {
+		t.Fatalf("unexpected templates: %+v", all)
+	}
+
+	words, _ := ListTemplates(dir, KindWord)
+	if len(words) != 1 || words[0].Kind != KindWord {
+		t.Fatalf("unexpected word templates: %+v", words)
+	}
+
+	rc, tmpl, err := OpenTemplate(dir, filepath.Join(dir, "Report.docx"))
+	if err != nil {
+		t.Fatalf("failed to open template: %v", err)
+	}
+	rc.Close()
+	if tmpl.Kind != KindWord {
+		t.Errorf("expected word template, got %s", tmpl.Kind)
+	}
+
+	if _, _, err := OpenTemplate(dir, filepath.Join(dir, "..", "Report.docx")); err == nil {
+		t.Error("templates outside the template directory must not be opened")
+	}
+}
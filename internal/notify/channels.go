@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WebhookChannel posts events as JSON to an HTTP endpoint
+type WebhookChannel struct {
+	URL    string
+	client *http.Client
+}
+
+// NewWebhookChannel creates a WebhookChannel for the given URL
+func NewWebhookChannel(url string) *WebhookChannel {
+	return &WebhookChannel{
+		URL:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send implements Channel
+func (c *WebhookChannel) Send(ev *Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(c.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MailSpoolChannel writes events as RFC 5322 messages into a spool directory,
+// to be picked up by a local MTA or mail client
+type MailSpoolChannel struct {
+	Dir  string
+	To   string
+	From string
+}
+
+// NewMailSpoolChannel creates a MailSpoolChannel writing into dir
+func NewMailSpoolChannel(dir, to string) *MailSpoolChannel {
+	return &MailSpoolChannel{
+		Dir:  dir,
+		To:   to,
+		From: "onlyoffice-connector@localhost",
+	}
+}
+
+// Send implements Channel
+func (c *MailSpoolChannel) Send(ev *Event) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", c.From)
+	if to := recipientsFor(ev, c.To); to != "" {
+		fmt.Fprintf(&buf, "To: %s\r\n", to)
+	}
+	// File and user names may contain line breaks or non-ASCII characters
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subjectFor(ev)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", ev.CreatedAt.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@onlyoffice-connector>\r\n", ev.ID)
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(bodyFor(ev), "\n", "\r\n"))
+
+	name := filepath.Join(c.Dir, ev.ID+".eml")
+	return os.WriteFile(name, buf.Bytes(), 0644)
+}
+
+// subjectFor returns a short subject line for an event
+func subjectFor(ev *Event) string {
+	switch ev.Kind {
+	case KindSaveError:
+		return "Document save failed: " + filepath.Base(ev.Path)
+	case KindForceSaveError:
+		return "Document force save failed: " + filepath.Base(ev.Path)
+	case KindMention:
+		return ev.From + " mentioned you in " + filepath.Base(ev.Path)
+	default:
+		return "Document notification: " + filepath.Base(ev.Path)
+	}
+}
+
+// recipientsFor returns the To header of an event, the mentioned users'
+// addresses or the configured recipient
+func recipientsFor(ev *Event, to string) string {
+	if ev.Kind != KindMention {
+		return to
+	}
+	var addresses []string
+	for _, email := range ev.Emails {
+		if strings.Contains(email, "@") {
+			addresses = append(addresses, email)
+		}
+	}
+	if len(addresses) == 0 {
+		return to
+	}
+	return strings.Join(addresses, ", ")
+}
+
+// bodyFor returns the plain-text message body for an event
+func bodyFor(ev *Event) string {
+	var b strings.Builder
+	if ev.Kind == KindMention {
+		fmt.Fprintf(&b, "%s mentioned you in a comment on %s:\n\n%s\n\n", ev.From, ev.Path, ev.Message)
+		fmt.Fprintf(&b, "Time: %s\n", ev.CreatedAt.Format(time.RFC3339))
+		if ev.Link != "" {
+			fmt.Fprintf(&b, "Open the comment: %s\n", ev.Link)
+		}
+		return b.String()
+	}
+	fmt.Fprintf(&b, "The Document Server reported that changes to %s could not be saved.\n\n", ev.Path)
+	fmt.Fprintf(&b, "Time: %s\n", ev.CreatedAt.Format(time.RFC3339))
+	if ev.Key != "" {
+		fmt.Fprintf(&b, "Document key: %s\n", ev.Key)
+	}
+	if len(ev.Users) > 0 {
+		fmt.Fprintf(&b, "Users: %s\n", strings.Join(ev.Users, ", "))
+	}
+	if ev.HasRecovery() {
+		fmt.Fprintf(&b, "\nA copy of the last version was kept as %s in the connector recovery folder.\n", ev.RecoveryFile)
+	}
+	return b.String()
+}
@@ -0,0 +1,267 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrEventNotFound = errors.New("notification not found")
+)
+
+// recoveryRetention is how long an acknowledged event with a recovery copy is
+// kept so the copy can still be downloaded. Other acknowledged events are
+// dropped right away.
+const recoveryRetention = 30 * 24 * time.Hour
+
+// Event kinds
+const (
+	KindSaveError      = "save_error"
+	KindForceSaveError = "forcesave_error"
+	KindMention        = "mention"
+)
+
+// Event represents a notification about a document
+type Event struct {
+	ID             string    `json:"id"`
+	Kind           string    `json:"kind"`
+	Path           string    `json:"path"`
+	Key            string    `json:"key,omitempty"`
+	Users          []string  `json:"users,omitempty"`  // Editing users, or the recipients of a mention
+	Emails         []string  `json:"emails,omitempty"` // Recipient addresses of a mention
+	From           string    `json:"from,omitempty"`   // Author of a mention
+	Message        string    `json:"message,omitempty"`
+	Link           string    `json:"link,omitempty"`         // Opens the document at the comment
+	RecoveryFile   string    `json:"recoveryFile,omitempty"` // File name inside the recovery directory
+	CreatedAt      time.Time `json:"createdAt"`
+	Acknowledged   bool      `json:"acknowledged"`
+	AcknowledgedAt time.Time `json:"acknowledgedAt,omitempty"`
+}
+
+// HasRecovery returns true if a recovered copy of the document is available
+func (e *Event) HasRecovery() bool {
+	return e.RecoveryFile != ""
+}
+
+// ShownTo returns true if the event is shown to user: mentions only to their
+// recipients, other events to everyone opening the document
+func (e *Event) ShownTo(user string) bool {
+	return e.Kind != KindMention || contains(e.Users, user)
+}
+
+// Channel delivers notifications to an outbound destination
+type Channel interface {
+	Send(ev *Event) error
+}
+
+// Notifier records document events and dispatches them to channels
+type Notifier struct {
+	mu          sync.Mutex
+	storePath   string
+	recoveryDir string
+	events      []*Event
+	channels    []Channel
+}
+
+// NewNotifier creates a Notifier storing its state below dataDir
+func NewNotifier(dataDir string, channels ...Channel) (*Notifier, error) {
+	n := &Notifier{
+		storePath:   filepath.Join(dataDir, "notifications.json"),
+		recoveryDir: filepath.Join(dataDir, "recovery"),
+		channels:    channels,
+	}
+
+	if err := os.MkdirAll(n.recoveryDir, 0755); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(n.storePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &n.events); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", n.storePath, err)
+		}
+	}
+	if n.prune(time.Now()) {
+		if err := n.save(); err != nil {
+			log.Printf("Notify: failed to save %s: %v", n.storePath, err)
+		}
+	}
+
+	return n, nil
+}
+
+// Report records an event, keeps the recovered content if provided and
+// dispatches the event to all channels. Channel failures are only logged.
+func (n *Notifier) Report(ev *Event, recovered io.Reader) error {
+	if ev.ID == "" {
+		ev.ID = newID()
+	}
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = time.Now()
+	}
+
+	if recovered != nil {
+		name := ev.ID + "-" + filepath.Base(ev.Path)
+		if err := writeFile(filepath.Join(n.recoveryDir, name), recovered); err != nil {
+			log.Printf("Notify: failed to keep recovery copy for %s: %v", ev.Path, err)
+		} else {
+			ev.RecoveryFile = name
+		}
+	}
+
+	n.mu.Lock()
+	n.events = append(n.events, ev)
+	err := n.save()
+	n.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range n.channels {
+		if err := ch.Send(ev); err != nil {
+			log.Printf("Notify: failed to deliver %s for %s: %v", ev.Kind, ev.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// Pending returns unacknowledged events for a document path, newest first
+func (n *Notifier) Pending(path string) []*Event {
+	return n.PendingFor(path, "")
+}
+
+// PendingFor returns the unacknowledged events of a document path shown to
+// user, newest first. Mentions are only returned to their recipients.
+func (n *Notifier) PendingFor(path, user string) []*Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var result []*Event
+	for _, ev := range n.events {
+		if ev.Path == path && !ev.Acknowledged && ev.ShownTo(user) {
+			result = append(result, ev)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Get returns the event with the given ID
+func (n *Notifier) Get(id string) (*Event, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, ev := range n.events {
+		if ev.ID == id {
+			return ev, nil
+		}
+	}
+	return nil, ErrEventNotFound
+}
+
+// Acknowledge marks an event as seen so it is no longer surfaced.
+// Acknowledged events are removed once their recovery copy has expired.
+func (n *Notifier) Acknowledge(id string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, ev := range n.events {
+		if ev.ID == id {
+			ev.Acknowledged = true
+			ev.AcknowledgedAt = time.Now()
+			n.prune(ev.AcknowledgedAt)
+			return n.save()
+		}
+	}
+	return ErrEventNotFound
+}
+
+// prune drops acknowledged events without a recovery copy and those whose
+// copy is older than recoveryRetention, deleting the copy. It returns true if
+// events were dropped; the caller holds the lock.
+func (n *Notifier) prune(now time.Time) bool {
+	kept := n.events[:0]
+	for _, ev := range n.events {
+		if !ev.Acknowledged || (ev.HasRecovery() && now.Sub(ev.AcknowledgedAt) < recoveryRetention) {
+			kept = append(kept, ev)
+			continue
+		}
+		if ev.HasRecovery() {
+			if err := os.Remove(filepath.Join(n.recoveryDir, filepath.Base(ev.RecoveryFile))); err != nil && !os.IsNotExist(err) {
+				log.Printf("Notify: failed to remove recovery copy %s: %v", ev.RecoveryFile, err)
+			}
+		}
+	}
+	for i := len(kept); i < len(n.events); i++ {
+		n.events[i] = nil
+	}
+	dropped := len(kept) < len(n.events)
+	n.events = kept
+	return dropped
+}
+
+// OpenRecovery returns a reader for the recovered copy of an event
+func (n *Notifier) OpenRecovery(ev *Event) (io.ReadCloser, error) {
+	if !ev.HasRecovery() {
+		return nil, ErrEventNotFound
+	}
+	return os.Open(filepath.Join(n.recoveryDir, filepath.Base(ev.RecoveryFile)))
+}
+
+// save persists the event list, must be called with the lock held
+func (n *Notifier) save() error {
+	data, err := json.MarshalIndent(n.events, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(n.storePath, data, 0644)
+}
+
+// writeFile copies content into a new file at path
+func writeFile(path string, content io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, content); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newID generates a random event identifier
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingChannel remembers delivered events
+type recordingChannel struct {
+	events []*Event
+}
+
+func (c *recordingChannel) Send(ev *Event) error {
+	c.events = append(c.events, ev)
+	return nil
+}
+
+// Unit test: Report keeps a recovery copy, dispatches and persists the event
+func TestReportKeepsRecoveryCopy(t *testing.T) {
+	dataDir := t.TempDir()
+	ch := &recordingChannel{}
+
+	n, err := NewNotifier(dataDir, ch)
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+
+	ev := &Event{Kind: KindSaveError, Path: "/vol1/docs/report.docx", Key: "abc"}
+	if err := n.Report(ev, strings.NewReader("recovered content")); err != nil {
+		t.Fatalf("failed to report event: %v", err)
+	}
+
+	if len(ch.events) != 1 {
+		t.Fatalf("expected 1 delivered event, got %d", len(ch.events))
+	}
+	if !ev.HasRecovery() {
+		t.Fatal("event should reference a recovery copy")
+	}
+
+	rc, err := n.OpenRecovery(ev)
+	if err != nil {
+		t.Fatalf("failed to open recovery copy: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "recovered content" {
+		t.Errorf("recovery content mismatch: got %q", data)
+	}
+
+	// Events survive a restart
+	reloaded, err := NewNotifier(dataDir)
+	if err != nil {
+		t.Fatalf("failed to reload notifier: %v", err)
+	}
+	if pending := reloaded.Pending(ev.Path); len(pending) != 1 || pending[0].ID != ev.ID {
+		t.Fatalf("expected reloaded pending event %s, got %v", ev.ID, pending)
+	}
+}
+
+// Unit test: Acknowledged events are no longer pending
+func TestAcknowledge(t *testing.T) {
+	n, err := NewNotifier(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+
+	ev := &Event{Kind: KindForceSaveError, Path: "/vol1/a.xlsx"}
+	if err := n.Report(ev, nil); err != nil {
+		t.Fatalf("failed to report event: %v", err)
+	}
+	if ev.HasRecovery() {
+		t.Error("event without content should not have a recovery copy")
+	}
+
+	if err := n.Acknowledge(ev.ID); err != nil {
+		t.Fatalf("failed to acknowledge: %v", err)
+	}
+	if pending := n.Pending(ev.Path); len(pending) != 0 {
+		t.Errorf("expected no pending events, got %d", len(pending))
+	}
+	if err := n.Acknowledge("missing"); err != ErrEventNotFound {
+		t.Errorf("expected ErrEventNotFound, got %v", err)
+	}
+}
+
+// Unit test: Acknowledged events are dropped, those with a recovery copy
+// once the copy has expired
+func TestAcknowledgePrunes(t *testing.T) {
+	dir := t.TempDir()
+	n, err := NewNotifier(dir)
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+
+	plain := &Event{Kind: KindSaveError, Path: "/vol1/a.docx"}
+	recovered := &Event{Kind: KindSaveError, Path: "/vol1/b.docx"}
+	if err := n.Report(plain, nil); err != nil {
+		t.Fatalf("failed to report event: %v", err)
+	}
+	if err := n.Report(recovered, strings.NewReader("last version")); err != nil {
+		t.Fatalf("failed to report event: %v", err)
+	}
+	for _, ev := range []*Event{plain, recovered} {
+		if err := n.Acknowledge(ev.ID); err != nil {
+			t.Fatalf("failed to acknowledge: %v", err)
+		}
+	}
+
+	if _, err := n.Get(plain.ID); err != ErrEventNotFound {
+		t.Errorf("acknowledged event should be dropped, got %v", err)
+	}
+	if _, err := n.Get(recovered.ID); err != nil {
+		t.Fatalf("event with a recovery copy should be kept: %v", err)
+	}
+
+	// Expire the copy and reload
+	n.mu.Lock()
+	recovered.AcknowledgedAt = time.Now().Add(-recoveryRetention)
+	n.save()
+	n.mu.Unlock()
+	reloaded, err := NewNotifier(dir)
+	if err != nil {
+		t.Fatalf("failed to reload notifier: %v", err)
+	}
+	if _, err := reloaded.Get(recovered.ID); err != ErrEventNotFound {
+		t.Errorf("expired event should be dropped, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "recovery", recovered.RecoveryFile)); !os.IsNotExist(err) {
+		t.Errorf("expired recovery copy should be removed, got %v", err)
+	}
+}
+
+// Unit test: MailSpoolChannel writes an RFC 5322 message
+func TestMailSpoolChannel(t *testing.T) {
+	dir := t.TempDir()
+	ch := NewMailSpoolChannel(dir, "admin@example.com")
+
+	n, err := NewNotifier(t.TempDir(), ch)
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+	ev := &Event{Kind: KindSaveError, Path: "/vol1/report.docx"}
+	if err := n.Report(ev, nil); err != nil {
+		t.Fatalf("failed to report event: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ev.ID+".eml"))
+	if err != nil {
+		t.Fatalf("spooled mail not found: %v", err)
+	}
+	msg := string(data)
+	for _, want := range []string{"To: admin@example.com\r\n", "Subject: Document save failed: report.docx\r\n", "/vol1/report.docx"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("spooled mail should contain %q", want)
+		}
+	}
+}
+
+// Unit test: Mentions are mailed to the recipients and only shown to them
+func TestMention(t *testing.T) {
+	dir := t.TempDir()
+	ch := NewMailSpoolChannel(dir, "admin@example.com")
+
+	n, err := NewNotifier(t.TempDir(), ch)
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+	ev := &Event{
+		Kind:    KindMention,
+		Path:    "/vol1/report.docx",
+		Users:   []string{"bob"},
+		Emails:  []string{"bob@example.com"},
+		From:    "Alice",
+		Message: "@Bob please check",
+	}
+	if err := n.Report(ev, nil); err != nil {
+		t.Fatalf("failed to report event: %v", err)
+	}
+
+	if pending := n.PendingFor(ev.Path, "bob"); len(pending) != 1 {
+		t.Errorf("expected the mention for bob, got %v", pending)
+	}
+	if pending := n.PendingFor(ev.Path, "carol"); len(pending) != 0 {
+		t.Errorf("expected no mention for carol, got %v", pending)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ev.ID+".eml"))
+	if err != nil {
+		t.Fatalf("spooled mail not found: %v", err)
+	}
+	msg := string(data)
+	for _, want := range []string{"To: bob@example.com\r\n", "Subject: Alice mentioned you in report.docx\r\n", "@Bob please check"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("spooled mail should contain %q", want)
+		}
+	}
+
+	// Line breaks in names cannot add headers
+	forged := &Event{Kind: KindMention, Path: "/vol1/report.docx", Users: []string{"bob"}, Emails: []string{"bob@example.com"}, From: "Alice\r\nBcc: eve@example.com"}
+	if err := n.Report(forged, nil); err != nil {
+		t.Fatalf("failed to report event: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, forged.ID+".eml"))
+	if header, _, _ := strings.Cut(string(data), "\r\n\r\n"); strings.Contains(header, "\r\nBcc:") {
+		t.Errorf("subject should not inject headers:\n%s", data)
+	}
+}
@@ -0,0 +1,191 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Open modes of the editor
+const (
+	ModeEdit      = "edit"      // Full editing with fast co-editing
+	ModeStrict    = "strict"    // Full editing with strict co-editing (changes shown after save)
+	ModeReview    = "review"    // Editing with track changes forced on
+	ModeComment   = "comment"   // Comments only
+	ModeFillForms = "fillForms" // Form filling only
+	ModeView      = "view"      // Read-only
+)
+
+// modeLabels holds the display names of the open modes
+var modeLabels = map[string]string{
+	ModeEdit:      "编辑",
+	ModeStrict:    "严格协作编辑",
+	ModeReview:    "审阅（修订）",
+	ModeComment:   "仅批注",
+	ModeFillForms: "填写表单",
+	ModeView:      "只读查看",
+}
+
+// ValidMode returns true if mode is a known open mode
+func ValidMode(mode string) bool {
+	_, ok := modeLabels[mode]
+	return ok
+}
+
+// ModeLabel returns the display name of a mode
+func ModeLabel(mode string) string {
+	return modeLabels[mode]
+}
+
+// Macro modes of macro-enabled documents, see editorConfig.customization.macrosMode
+const (
+	MacrosEnable  = "enable"  // Run macros automatically
+	MacrosWarn    = "warn"    // Ask the user before running macros
+	MacrosDisable = "disable" // Never run macros
+)
+
+// Rule restricts the open modes for some users and folders.
+// Empty Users or Paths match everyone or everything.
+type Rule struct {
+	Users   []string `json:"users,omitempty"`
+	Paths   []string `json:"paths,omitempty"`
+	Modes   []string `json:"modes"`
+	Default string   `json:"default,omitempty"` // Mode used when none is requested
+}
+
+// MacroRule sets how macros run for some users and folders.
+// Empty Users or Paths match everyone or everything.
+type MacroRule struct {
+	Users []string `json:"users,omitempty"`
+	Paths []string `json:"paths,omitempty"`
+	Mode  string   `json:"mode"` // enable, warn or disable
+}
+
+// Policy holds the permission rules, the first matching rule applies
+type Policy struct {
+	Rules  []Rule      `json:"rules"`
+	Macros []MacroRule `json:"macros,omitempty"`
+}
+
+// Load reads a policy file. A missing file yields an empty policy that allows
+// everything; an invalid one returns an error and no policy, callers must not
+// fall back to allowing everything.
+func Load(path string) (*Policy, error) {
+	p := &Policy{}
+	if path == "" {
+		return p, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	for i, rule := range p.Rules {
+		for _, mode := range append(rule.Modes, rule.Default) {
+			if mode != "" && !ValidMode(mode) {
+				return nil, fmt.Errorf("invalid policy %s: rule %d: unknown mode %q", path, i+1, mode)
+			}
+		}
+	}
+	for i, rule := range p.Macros {
+		if rule.Mode != MacrosEnable && rule.Mode != MacrosWarn && rule.Mode != MacrosDisable {
+			return nil, fmt.Errorf("invalid policy %s: macro rule %d: unknown mode %q", path, i+1, rule.Mode)
+		}
+	}
+	return p, nil
+}
+
+// Match returns the first rule matching user and path
+func (p *Policy) Match(user, path string) (*Rule, bool) {
+	if p == nil {
+		return nil, false
+	}
+	for i := range p.Rules {
+		if p.Rules[i].matches(user, path) {
+			return &p.Rules[i], true
+		}
+	}
+	return nil, false
+}
+
+// Resolve picks the open mode for user and path among the modes the format
+// supports (in order of preference). The requested mode is used if allowed,
+// otherwise the rule default or the first allowed mode. It returns false
+// if the policy allows none of the supported modes.
+func (p *Policy) Resolve(user, path, requested string, supported []string) (string, []string, bool) {
+	rule, matched := p.Match(user, path)
+
+	var allowed []string
+	for _, mode := range supported {
+		if !matched || contains(rule.Modes, mode) {
+			allowed = append(allowed, mode)
+		}
+	}
+	if len(allowed) == 0 {
+		return "", nil, false
+	}
+
+	if contains(allowed, requested) {
+		return requested, allowed, true
+	}
+	if matched && contains(allowed, rule.Default) {
+		return rule.Default, allowed, true
+	}
+	return allowed[0], allowed, true
+}
+
+// MacrosMode returns the macro mode of the first macro rule matching user
+// and path, empty to keep the editor default
+func (p *Policy) MacrosMode(user, path string) string {
+	if p == nil {
+		return ""
+	}
+	for _, rule := range p.Macros {
+		if matches(rule.Users, rule.Paths, user, path) {
+			return rule.Mode
+		}
+	}
+	return ""
+}
+
+// matches reports whether the rule applies to user and path
+func (r *Rule) matches(user, path string) bool {
+	return matches(r.Users, r.Paths, user, path)
+}
+
+// matches reports whether user and path are selected by a list of users and
+// folder prefixes
+func matches(users, paths []string, user, path string) bool {
+	if len(users) > 0 && !contains(users, user) {
+		return false
+	}
+	if len(paths) == 0 {
+		return true
+	}
+	clean := filepath.Clean("/" + strings.TrimPrefix(path, "/"))
+	for _, prefix := range paths {
+		prefix = filepath.Clean("/" + strings.TrimPrefix(prefix, "/"))
+		if clean == prefix || prefix == "/" || strings.HasPrefix(clean, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
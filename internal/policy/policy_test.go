@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var editable = []string{ModeEdit, ModeStrict, ModeReview, ModeComment, ModeView}
+
+// Unit test: Modes are resolved from the first matching rule
+func TestResolve(t *testing.T) {
+	p := &Policy{Rules: []Rule{
+		{Users: []string{"bob"}, Paths: []string{"/vol1/contracts"}, Modes: []string{ModeComment, ModeView}, Default: ModeComment},
+		{Paths: []string{"/vol1/archive"}, Modes: []string{ModeView}},
+	}}
+
+	tests := []struct {
+		user, path, requested string
+		want                  string
+	}{
+		{"bob", "/vol1/contracts/a.docx", "", ModeComment},
+		{"bob", "/vol1/contracts/a.docx", ModeView, ModeView},
+		{"bob", "/vol1/contracts/a.docx", ModeEdit, ModeComment},
+		{"alice", "/vol1/contracts/a.docx", ModeReview, ModeReview},
+		{"alice", "/vol1/contracts-old/a.docx", "", ModeEdit},
+		{"alice", "/vol1/archive/a.docx", ModeEdit, ModeView},
+	}
+	for _, tt := range tests {
+		got, _, ok := p.Resolve(tt.user, tt.path, tt.requested, editable)
+		if !ok || got != tt.want {
+			t.Errorf("Resolve(%s, %s, %q) = %q %v, want %q", tt.user, tt.path, tt.requested, got, ok, tt.want)
+		}
+	}
+
+	// Rules that allow none of the supported modes deny access
+	if _, _, ok := p.Resolve("bob", "/vol1/contracts/form.oform", "", []string{ModeFillForms}); ok {
+		t.Error("expected no allowed mode")
+	}
+
+	// Without a policy everything the format supports is allowed
+	var empty *Policy
+	if mode, allowed, ok := empty.Resolve("bob", "/x.docx", "", editable); !ok || mode != ModeEdit || len(allowed) != len(editable) {
+		t.Errorf("unexpected resolution without policy: %q %v %v", mode, allowed, ok)
+	}
+}
+
+// Unit test: Policy files are validated
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	if p, err := Load(filepath.Join(dir, "missing.json")); err != nil || len(p.Rules) != 0 {
+		t.Errorf("missing file should give an empty policy, got %v %v", p, err)
+	}
+
+	valid := filepath.Join(dir, "policy.json")
+	os.WriteFile(valid, []byte(`{"rules": [{"paths": ["/vol1/archive"], "modes": ["view"]}]}`), 0644)
+	if p, err := Load(valid); err != nil || len(p.Rules) != 1 {
+		t.Errorf("unexpected result %v %v", p, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`{"rules": [{"modes": ["delete"]}]}`), 0644)
+	if p, err := Load(invalid); err == nil || p != nil {
+		t.Errorf("expected error and no policy for unknown mode, got %v %v", p, err)
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	os.WriteFile(broken, []byte(`{"rules": [`), 0644)
+	if p, err := Load(broken); err == nil || p != nil {
+		t.Errorf("expected error and no policy for invalid JSON, got %v %v", p, err)
+	}
+}
+
+// Unit test: Macro modes come from the first matching macro rule
+func TestMacrosMode(t *testing.T) {
+	p := &Policy{Macros: []MacroRule{
+		{Users: []string{"admin"}, Mode: MacrosEnable},
+		{Paths: []string{"/vol1/inbox"}, Mode: MacrosDisable},
+	}}
+
+	tests := []struct {
+		user, path, want string
+	}{
+		{"admin", "/vol1/inbox/a.xlsm", MacrosEnable},
+		{"bob", "/vol1/inbox/a.xlsm", MacrosDisable},
+		{"bob", "/vol1/finance/a.xlsm", ""},
+	}
+	for _, tt := range tests {
+		if got := p.MacrosMode(tt.user, tt.path); got != tt.want {
+			t.Errorf("MacrosMode(%s, %s) = %q, want %q", tt.user, tt.path, got, tt.want)
+		}
+	}
+
+	invalid := filepath.Join(t.TempDir(), "policy.json")
+	os.WriteFile(invalid, []byte(`{"rules": [], "macros": [{"mode": "sometimes"}]}`), 0644)
+	if _, err := Load(invalid); err == nil {
+		t.Error("expected error for unknown macro mode")
+	}
+}
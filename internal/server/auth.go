@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	jwtpkg "onlyoffice-fnos/internal/jwt"
+)
+
+// jwtEnabled returns true if Document Server requests must be signed
+func (s *Server) jwtEnabled() bool {
+	return s.keyring.Enabled()
+}
+
+// signToken signs claims with the primary Document Server secret
+func (s *Server) signToken(claims map[string]interface{}) (string, error) {
+	return s.jwtManager.Sign(s.keyring.Primary.Secret, claims, s.keyring.Primary.ID)
+}
+
+// signTokenWithExpiry signs claims with the primary secret and an expiry (0 for none)
+func (s *Server) signTokenWithExpiry(claims map[string]interface{}, expiry time.Duration) (string, error) {
+	return s.jwtManager.SignWithExpiry(s.keyring.Primary.Secret, claims, expiry, s.keyring.Primary.ID)
+}
+
+// verifyToken validates a token signed with the primary or any accepted secret
+func (s *Server) verifyToken(token string) (map[string]interface{}, error) {
+	return s.jwtManager.VerifyWithKeys(s.keyring.Keys(), token)
+}
+
+// tokenFromHeader extracts the token from the configured JWT header
+func (s *Server) tokenFromHeader(r *http.Request) string {
+	value := strings.TrimSpace(r.Header.Get(s.settings.GetJWTHeader()))
+	if len(value) > 7 && strings.EqualFold(value[:7], "Bearer ") {
+		value = strings.TrimSpace(value[7:])
+	}
+	return value
+}
+
+// verifyRequest validates the token of a Document Server request and returns
+// the authoritative payload. A token in the body (JWT_IN_BODY) carries the
+// fields directly, while a header token wraps them in a "payload" claim.
+func (s *Server) verifyRequest(r *http.Request, bodyToken string) (map[string]interface{}, error) {
+	if bodyToken != "" {
+		return s.verifyToken(bodyToken)
+	}
+
+	headerToken := s.tokenFromHeader(r)
+	if headerToken == "" {
+		return nil, jwtpkg.ErrInvalidToken
+	}
+
+	claims, err := s.verifyToken(headerToken)
+	if err != nil {
+		return nil, err
+	}
+	if payload, ok := claims["payload"].(map[string]interface{}); ok {
+		return payload, nil
+	}
+	return claims, nil
+}
+
+// decodeClaims decodes verified claims into a request struct
+func decodeClaims(claims map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return jwtpkg.ErrInvalidClaim
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return jwtpkg.ErrInvalidClaim
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"path/filepath"
 	"time"
 
 	jwtpkg "onlyoffice-fnos/internal/jwt"
@@ -72,17 +74,10 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Callback received: path=%s, status=%d, key=%s", filePath, req.Status, req.Key)
-
-	// Verify JWT token if secret is configured
-	if s.settings != nil && s.settings.DocumentServerSecret != "" {
-		if req.Token == "" {
-			log.Printf("Callback error: missing JWT token")
-			s.respondJSON(w, http.StatusOK, &CallbackResponse{Error: 1})
-			return
-		}
-
-		_, err := s.jwtManager.Verify(s.settings.DocumentServerSecret, req.Token)
+	// Verify JWT token if secret is configured. The verified claims replace
+	// the unsigned body so that only signed fields are trusted.
+	if s.jwtEnabled() {
+		claims, err := s.verifyRequest(r, req.Token)
 		if err != nil {
 			log.Printf("Callback error: invalid JWT token: %v", err)
 			if err == jwtpkg.ErrExpiredToken {
@@ -91,13 +86,30 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 			s.respondJSON(w, http.StatusOK, &CallbackResponse{Error: 1})
 			return
 		}
+
+		var verified CallbackRequest
+		if err := decodeClaims(claims, &verified); err != nil {
+			log.Printf("Callback error: invalid JWT payload: %v", err)
+			s.respondJSON(w, http.StatusOK, &CallbackResponse{Error: 1})
+			return
+		}
+		req = verified
 	}
 
+	// A renamed file keeps its session, the registry knows the current path
+	if sess, ok := s.sessions.Lookup(req.Key); ok && sess.Path != filePath {
+		log.Printf("Callback: session %s moved from %s to %s", req.Key, filePath, sess.Path)
+		filePath = sess.Path
+	}
+
+	log.Printf("Callback received: path=%s, status=%d, key=%s", filePath, req.Status, req.Key)
+
 	// Handle different statuses
 	switch req.Status {
 	case StatusEditing:
-		// Document is being edited, nothing to do
+		// Document is being edited, the session is still alive
 		log.Printf("Document %s is being edited", filePath)
+		s.sessions.Touch(req.Key, time.Time{})
 
 	case StatusSaved, StatusForceSave:
 		// Document is ready for saving
@@ -107,20 +119,31 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := s.saveDocument(filePath, req.URL); err != nil {
+		if err := s.saveEditedDocument(filePath, req.URL, req.Filetype, req.Key, r.URL.Query()); err != nil {
 			log.Printf("Callback error: failed to save document: %v", err)
 			s.respondJSON(w, http.StatusOK, &CallbackResponse{Error: 1})
 			return
 		}
 		log.Printf("Document %s saved successfully", filePath)
+		if req.Status == StatusSaved {
+			s.sessions.Remove(req.Key)
+		} else if fileInfo, err := s.fileService.GetFileInfo(filePath); err == nil {
+			// The session continues on the version just saved
+			s.sessions.Touch(req.Key, fileInfo.ModTime)
+		}
 
 	case StatusClosed:
 		// Document closed with no changes
 		log.Printf("Document %s closed with no changes", filePath)
+		s.sessions.Remove(req.Key)
 
 	case StatusSaveError, StatusForceSaveError:
 		// Save error occurred
 		log.Printf("Document %s save error reported by Document Server", filePath)
+		s.reportSaveError(filePath, &req)
+		if req.Status == StatusSaveError {
+			s.sessions.Remove(req.Key)
+		}
 
 	default:
 		log.Printf("Unknown callback status %d for document %s", req.Status, filePath)
@@ -156,6 +179,45 @@ func (s *Server) saveDocument(filePath, documentURL string) error {
 	return nil
 }
 
+// saveEditedDocument saves a document from the editor. Documents opened in
+// place carry the format of their content in the inplace parameter and are
+// converted back to it from the format the editor saved in; everything else
+// is saved as is. csv and txt files are written with the encoding given in
+// the callback parameters, or else the one of the file being replaced.
+func (s *Server) saveEditedDocument(filePath, documentURL, fileType, key string, params url.Values) error {
+	ext := params.Get("inplace")
+	if ext == "" || !s.formatManager.IsConvertible(ext) {
+		return s.saveDocument(filePath, documentURL)
+	}
+	if fileType == "" {
+		fileType = s.formatManager.GetConvertTarget(ext)
+	}
+	if fileType == ext {
+		return s.saveDocument(filePath, documentURL)
+	}
+
+	textOpts := s.textOptionsFor(params, filePath, ext)
+	content, err := s.convertDocument(&ConvertRequest{
+		Filetype:   fileType,
+		Key:        fmt.Sprintf("%s_%s_%d", key, ext, time.Now().UnixNano()),
+		Outputtype: ext,
+		Title:      filepath.Base(filePath),
+		URL:        documentURL,
+		CodePage:   textOpts.CodePage,
+		Delimiter:  textOpts.Delimiter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to convert %s back to %s: %w", fileType, ext, err)
+	}
+	defer content.Close()
+
+	if err := s.fileService.SaveFile(filePath, content); err != nil {
+		return fmt.Errorf("failed to save document: %w", err)
+	}
+	log.Printf("Converted %s back from %s", filePath, fileType)
+	return nil
+}
+
 // SaveDocumentFromReader saves document content from a reader (for testing)
 func (s *Server) SaveDocumentFromReader(filePath string, content io.Reader) error {
 	return s.fileService.SaveFile(filePath, content)
@@ -16,6 +16,8 @@ import (
 	"onlyoffice-fnos/internal/file"
 	"onlyoffice-fnos/internal/format"
 	"onlyoffice-fnos/internal/jwt"
+	"onlyoffice-fnos/internal/notify"
+	"onlyoffice-fnos/internal/policy"
 )
 
 // Property 3: 文档保存完整性
@@ -211,6 +213,70 @@ func TestCallbackWithJWTVerification(t *testing.T) {
 	}
 }
 
+// Test callback with a header token whose payload claim is authoritative
+func TestCallbackWithHeaderToken(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "callback_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	jwtManager := jwt.NewManager()
+	secret := jwtManager.GenerateSecret()
+
+	server := New(&Config{
+		Settings: &config.Settings{
+			DocumentServerURL:    "http://example.com",
+			DocumentServerSecret: secret,
+			JWTHeader:            "AuthorizationJwt",
+		},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwtManager,
+		BaseURL:       "http://localhost:10099",
+	})
+
+	// The signed payload says "editing" while the unsigned body claims a save
+	token, _ := jwtManager.Sign(secret, map[string]interface{}{
+		"payload": map[string]interface{}{
+			"key":    "test-key",
+			"status": StatusEditing,
+		},
+	})
+	reqBody, _ := json.Marshal(CallbackRequest{
+		Key:    "test-key",
+		Status: StatusSaved,
+		URL:    "http://attacker.invalid/doc",
+	})
+
+	send := func(header, value string) CallbackResponse {
+		req := httptest.NewRequest("POST", "/callback?path=test.docx", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		var resp CallbackResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return resp
+	}
+
+	if resp := send("AuthorizationJwt", "Bearer "+token); resp.Error != 0 {
+		t.Fatalf("Expected error 0 for valid header token, got %d", resp.Error)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "test.docx")); !os.IsNotExist(err) {
+		t.Fatal("Unsigned body fields must not trigger a save")
+	}
+	if resp := send("Authorization", "Bearer "+token); resp.Error != 1 {
+		t.Fatalf("Expected error 1 for token in the wrong header, got %d", resp.Error)
+	}
+	if resp := send("", ""); resp.Error != 1 {
+		t.Fatalf("Expected error 1 without token, got %d", resp.Error)
+	}
+}
+
 // Test callback status handling
 func TestCallbackStatusHandling(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "callback_test_*")
@@ -258,6 +324,151 @@ func TestCallbackStatusHandling(t *testing.T) {
 	}
 }
 
+// Test save error callback records a notification with a recovery copy
+func TestCallbackSaveErrorNotification(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "callback_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("last version"))
+	}))
+	defer mockDocServer.Close()
+
+	notifier, err := notify.NewNotifier(filepath.Join(tempDir, "data"))
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+
+	server := New(&Config{
+		Settings:      &config.Settings{DocumentServerURL: mockDocServer.URL},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwt.NewManager(),
+		Notifier:      notifier,
+		BaseURL:       "http://localhost:10099",
+	})
+
+	callbackReq := CallbackRequest{
+		Key:    "test-key",
+		Status: StatusSaveError,
+		URL:    mockDocServer.URL + "/document",
+		Users:  []string{"user1"},
+	}
+	reqBody, _ := json.Marshal(callbackReq)
+
+	req := httptest.NewRequest("POST", "/callback?path=test.docx", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	server.ServeHTTP(rec, req)
+
+	var resp CallbackResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Error != 0 {
+		t.Fatalf("Expected error 0, got %d", resp.Error)
+	}
+
+	pending := notifier.Pending("test.docx")
+	if len(pending) != 1 {
+		t.Fatalf("Expected 1 pending notification, got %d", len(pending))
+	}
+	if pending[0].Kind != notify.KindSaveError || !pending[0].HasRecovery() {
+		t.Fatalf("Unexpected notification: %+v", pending[0])
+	}
+
+	// The recovery copy is downloadable
+	req = httptest.NewRequest("GET", "/recovery?id="+pending[0].ID, nil)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "last version" {
+		t.Fatalf("Unexpected recovery download: %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Users the policy hides the document from get neither the copy nor the notice
+	server.policy = &policy.Policy{Rules: []policy.Rule{{Users: []string{"mallory"}, Paths: []string{"test.docx"}, Modes: []string{}}}}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/recovery?user_id=mallory&id="+pending[0].ID, nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a user without access, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("POST", "/notifications/ack?user_id=mallory&id="+pending[0].ID, nil))
+	if rec.Code != http.StatusForbidden || len(notifier.Pending("test.docx")) != 1 {
+		t.Errorf("Expected 403 for dismissing without access, got %d", rec.Code)
+	}
+
+	// Mentions are dismissed only by their recipients
+	mention := &notify.Event{Kind: notify.KindMention, Path: "test.docx", Users: []string{"bob"}}
+	notifier.Report(mention, nil)
+	for _, tc := range []struct {
+		user string
+		want int
+	}{{"carol", http.StatusForbidden}, {"bob", http.StatusOK}} {
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/notifications/ack?user_id="+tc.user+"&id="+mention.ID, nil))
+		if rec.Code != tc.want {
+			t.Errorf("Expected %d when %s dismisses the mention, got %d", tc.want, tc.user, rec.Code)
+		}
+	}
+}
+
+// Test ODF files edited in place are opened in the editor and converted back on save
+func TestCallbackEditInPlace(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "notes.odt"), []byte("odt"), 0644)
+
+	var conversion ConvertRequest
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ConvertService.ashx":
+			json.NewDecoder(r.Body).Decode(&conversion)
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/converted.odt"})
+		case "/converted.odt":
+			w.Write([]byte("converted odt"))
+		default:
+			w.Write([]byte("edited docx"))
+		}
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+	server.settings.BaseURL = "http://connector:10099"
+	editInPlace := true
+	if err := server.formatManager.Apply([]format.Override{{Extension: "odt", EditInPlace: &editInPlace}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/editor?path=notes.odt&mode=edit", nil))
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte(`"mode":"edit"`)) {
+		t.Fatalf("Expected the odt file to open for editing, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte(`/callback?path=notes.odt\u0026inplace=odt`)) {
+		t.Errorf("Expected the in-place format in the callback URL")
+	}
+
+	reqBody, _ := json.Marshal(&CallbackRequest{Key: "odt-key", Status: StatusSaved, URL: mockDocServer.URL + "/edited.docx", Filetype: "docx"})
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("POST", "/callback?path=notes.odt&inplace=odt", bytes.NewReader(reqBody)))
+
+	var resp CallbackResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Error != 0 {
+		t.Fatalf("Expected error 0, got %d", resp.Error)
+	}
+	if conversion.Filetype != "docx" || conversion.Outputtype != "odt" || conversion.URL != mockDocServer.URL+"/edited.docx" {
+		t.Errorf("Unexpected conversion request %+v", conversion)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tempDir, "notes.odt")); string(content) != "converted odt" {
+		t.Errorf("Expected the converted odt to be saved, got %q", content)
+	}
+}
+
 // Helper function to create a test server
 func createTestServer(t *testing.T, tempDir string) *Server {
 	settings := &config.Settings{
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CommandResponse represents the command service API response
+type CommandResponse struct {
+	Error   int    `json:"error"`
+	Key     string `json:"key,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// callCommandService sends a command to the Document Server command service,
+// e.g. {"c": "meta", "key": ..., "meta": {"title": ...}}
+func (s *Server) callCommandService(command map[string]interface{}) (*CommandResponse, error) {
+	if s.settings == nil || s.settings.DocumentServerURL == "" {
+		return nil, fmt.Errorf("document server URL not configured")
+	}
+
+	apiURL := strings.TrimSuffix(s.settings.DocumentServerURL, "/") + "/coauthoring/CommandService.ashx"
+
+	var token string
+	if s.jwtEnabled() {
+		var err error
+		token, err = s.signToken(command)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign command: %w", err)
+		}
+	}
+
+	body := make(map[string]interface{}, len(command)+1)
+	for k, v := range command {
+		body[k] = v
+	}
+	if token != "" {
+		body["token"] = token
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal command: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", apiURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set(s.settings.GetJWTHeader(), "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var cmdResp CommandResponse
+	if err := json.Unmarshal(respBody, &cmdResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if cmdResp.Error != 0 {
+		return &cmdResp, fmt.Errorf("command service error code: %d", cmdResp.Error)
+	}
+	return &cmdResp, nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"io"
+	"log"
+	"strconv"
+
+	"onlyoffice-fnos/internal/convcache"
+)
+
+// convertCached converts a document, reusing an earlier conversion of the
+// same content. The conversion key is derived from the content so that the
+// Document Server can reuse its result too. Password protected documents are
+// not cached: their decrypted result must not be served without the password.
+func (s *Server) convertCached(filePath string, convReq *ConvertRequest) (io.ReadCloser, error) {
+	if convReq.Password != "" || s.isEncrypted(filePath) {
+		return s.convertDocument(convReq)
+	}
+	hash, err := s.hashFile(filePath)
+	if err != nil {
+		log.Printf("Conversion cache: failed to hash %s: %v", filePath, err)
+		return s.convertDocument(convReq)
+	}
+
+	key := convcache.Key(hash, convReq.Filetype, convReq.Outputtype,
+		strconv.Itoa(convReq.CodePage), strconv.Itoa(convReq.Delimiter))
+	if content, ok := s.conversions.Open(key); ok {
+		log.Printf("Using cached conversion of %s to %s", filePath, convReq.Outputtype)
+		return content, nil
+	}
+
+	convReq.Key = key
+	content, err := s.convertDocument(convReq)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := s.conversions.Store(key, content)
+	if err != nil {
+		// The Document Server keeps its result under the same key
+		log.Printf("Conversion cache: failed to store conversion of %s: %v", filePath, err)
+		convReq.Token = ""
+		return s.convertDocument(convReq)
+	}
+	return cached, nil
+}
+
+// hashFile returns the hash of a file content
+func (s *Server) hashFile(filePath string) (string, error) {
+	content, err := s.fileService.GetFileContent(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	return convcache.HashContent(content)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"onlyoffice-fnos/internal/config"
+)
+
+// convertedSuffix is added to the name of a conversion that must not replace
+// an existing file, e.g. "report (converted).docx"
+const convertedSuffix = " (converted)"
+
+// errTargetExists is returned when the conversion target exists and the user
+// has to choose between replacing it and saving under another name
+var errTargetExists = errors.New("conversion target already exists")
+
+// requestConflict returns the choice made on the convert page, or else the
+// configured behavior when the conversion target exists
+func (s *Server) requestConflict(r *http.Request) string {
+	if conflict := r.FormValue("conflict"); conflict == config.ConflictOverwrite || conflict == config.ConflictRename {
+		return conflict
+	}
+	return s.settings.GetConvertConflict()
+}
+
+// requestOriginal returns the choice made on the convert page, or else the
+// configured handling of the original after converting
+func (s *Server) requestOriginal(r *http.Request) string {
+	if original := r.FormValue("original"); config.ValidOriginal(original) {
+		return original
+	}
+	return s.settings.GetConvertOriginal()
+}
+
+// allowedOriginal returns the handling of the original after converting.
+// Archiving and deleting need the edit mode. A configured default the user
+// may not apply falls back to keeping the original; it returns false if the
+// user chose it.
+func (s *Server) allowedOriginal(r *http.Request, userID, filePath string) (string, bool) {
+	original := s.requestOriginal(r)
+	if original == config.OriginalKeep || s.canWrite(userID, filePath) {
+		return original, true
+	}
+	if r.FormValue("original") == original {
+		return "", false
+	}
+	return config.OriginalKeep, true
+}
+
+// conversionTarget returns the path to save the conversion of filePath to.
+// A document open in the editor is never replaced.
+func (s *Server) conversionTarget(filePath, targetFormat, conflict string) (string, error) {
+	target := s.buildTargetPath(filePath, targetFormat)
+	if !s.fileService.Exists(target) {
+		return target, nil
+	}
+	if conflict == config.ConflictOverwrite && (target == filePath || s.isOpen(target)) {
+		conflict = config.ConflictRename
+	}
+	switch conflict {
+	case config.ConflictOverwrite:
+		return target, nil
+	case config.ConflictRename:
+		return s.fileService.UniquePath(convertedName(target))
+	}
+	return "", errTargetExists
+}
+
+// convertedName adds convertedSuffix to the name of a file
+func convertedName(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + convertedSuffix + ext
+}
+
+// isOpen returns true if a document has an editing session
+func (s *Server) isOpen(filePath string) bool {
+	fileInfo, err := s.fileService.GetFileInfo(filePath)
+	if err != nil {
+		return false
+	}
+	_, ok := s.sessions.KeyForPath(filePath, fileInfo.ModTime)
+	return ok
+}
+
+// archiveDir returns the folder the original of filePath is archived in
+func (s *Server) archiveDir(filePath string) string {
+	dir := s.settings.GetConvertArchiveDir()
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(filePath), dir)
+}
+
+// handleOriginal archives or deletes the original after a successful
+// conversion. It returns the archived path, empty if the original is kept or
+// deleted. A document open in the editor is kept.
+func (s *Server) handleOriginal(filePath, original string) (string, error) {
+	if original == config.OriginalKeep {
+		return "", nil
+	}
+	if s.isOpen(filePath) {
+		log.Printf("Keeping original %s, it is open in the editor", filePath)
+		return "", nil
+	}
+
+	switch original {
+	case config.OriginalArchive:
+		archived, err := s.fileService.Move(filePath, s.archiveDir(filePath))
+		if err != nil {
+			return "", err
+		}
+		s.userdata.Rename(filePath, archived)
+		s.fileids.Rename(filePath, archived)
+		log.Printf("Archived original %s as %s", filePath, archived)
+		return archived, nil
+	case config.OriginalDelete:
+		if err := s.fileService.Remove(filePath); err != nil {
+			return "", err
+		}
+		s.sessions.RemovePath(filePath)
+		s.userdata.Remove(filePath)
+		s.fileids.Remove(filePath)
+		log.Printf("Deleted original %s", filePath)
+	}
+	return "", nil
+}
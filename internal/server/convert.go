@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,20 +13,32 @@ import (
 	"strings"
 	"time"
 
+	"onlyoffice-fnos/internal/editor"
 	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/policy"
 )
 
 // ConvertRequest represents a conversion request
 type ConvertRequest struct {
-	Async      bool   `json:"async"`
-	Filetype   string `json:"filetype"`
-	Key        string `json:"key"`
-	Outputtype string `json:"outputtype"`
-	Title      string `json:"title"`
-	URL        string `json:"url"`
-	Token      string `json:"token,omitempty"`
+	Async      bool              `json:"async"`
+	Filetype   string            `json:"filetype"`
+	Key        string            `json:"key"`
+	Outputtype string            `json:"outputtype"`
+	Title      string            `json:"title"`
+	URL        string            `json:"url"`
+	Password   string            `json:"password,omitempty"`  // Opens password protected documents
+	CodePage   int               `json:"codePage,omitempty"`  // Encoding of csv and txt files
+	Delimiter  int               `json:"delimiter,omitempty"` // Column separator of csv files
+	Thumbnail  *ThumbnailOptions `json:"thumbnail,omitempty"` // Renders the first page as an image
+	Token      string            `json:"token,omitempty"`
 }
 
+// errConversionPassword is returned when the conversion API rejects the document password
+var errConversionPassword = errors.New("document password missing or incorrect")
+
+// conversionErrorPassword is the conversion API error code for a wrong or missing password
+const conversionErrorPassword = -5
+
 // ConvertResponse represents the conversion API response
 type ConvertResponse struct {
 	EndConvert bool   `json:"endConvert"`
@@ -63,6 +76,17 @@ func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reading the original and writing the conversion next to it must be allowed
+	userID, _ := s.requestUser(w, r)
+	if !s.canRead(userID, filePath) {
+		log.Printf("Policy denies %s access to %s", userID, filePath)
+		s.respondFormError(w, r, http.StatusForbidden, "您没有转换此文档的权限")
+		return
+	}
+
+	// Convert mislabelled files from the type of their content
+	fileInfo, _ = s.sniffFileInfo(fileInfo)
+
 	// Check if format is convertible
 	if !s.formatManager.IsConvertible(fileInfo.Extension) {
 		s.respondError(w, http.StatusBadRequest, "File format is not convertible")
@@ -82,61 +106,58 @@ func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build download URL for the source file
-	downloadURL := s.buildDownloadURL(filePath)
+	// Choose the target path before converting, replacing an existing file
+	// may need the user's decision
+	targetPath, err := s.conversionTarget(filePath, targetFormat, s.requestConflict(r))
+	if err == errTargetExists {
+		name := filepath.Base(s.buildTargetPath(filePath, targetFormat))
+		s.respondFormError(w, r, http.StatusConflict, fmt.Sprintf("目标文件 %s 已存在，请选择覆盖或另存为新文件", name))
+		return
+	}
+	if err != nil {
+		log.Printf("Convert error: failed to choose target file: %v", err)
+		s.respondError(w, http.StatusInternalServerError, "Failed to choose target file")
+		return
+	}
+	if !s.canWrite(userID, targetPath) {
+		log.Printf("Policy denies %s writing %s", userID, targetPath)
+		s.respondFormError(w, r, http.StatusForbidden, "您没有在此文件夹保存转换结果的权限")
+		return
+	}
+	original, ok := s.allowedOriginal(r, userID, filePath)
+	if !ok {
+		log.Printf("Policy denies %s removing %s", userID, filePath)
+		s.respondFormError(w, r, http.StatusForbidden, "您没有归档或删除原文件的权限")
+		return
+	}
 
-	// Generate unique key for conversion
-	conversionKey := fmt.Sprintf("convert_%s_%d", filePath, time.Now().UnixNano())
+	// Import csv and txt files with the chosen or detected encoding
+	textOpts := s.textOptionsFor(r.Form, filePath, fileInfo.Extension)
 
-	// Build conversion request
-	convReq := &ConvertRequest{
-		Async:      false, // Synchronous conversion
+	// Convert the file through its download URL, or reuse the conversion of
+	// the same content
+	convertedContent, err := s.convertCached(filePath, &ConvertRequest{
 		Filetype:   fileInfo.Extension,
-		Key:        conversionKey,
+		Key:        s.conversionKey("convert", filePath),
 		Outputtype: targetFormat,
 		Title:      fileInfo.Name,
-		URL:        downloadURL,
-	}
-
-	// Sign request with JWT if secret is configured
-	if s.settings.DocumentServerSecret != "" {
-		claims := map[string]interface{}{
-			"async":      convReq.Async,
-			"filetype":   convReq.Filetype,
-			"key":        convReq.Key,
-			"outputtype": convReq.Outputtype,
-			"title":      convReq.Title,
-			"url":        convReq.URL,
-		}
-		token, err := s.jwtManager.Sign(s.settings.DocumentServerSecret, claims)
-		if err != nil {
-			log.Printf("Convert error: failed to sign request: %v", err)
-			s.respondError(w, http.StatusInternalServerError, "Failed to sign conversion request")
-			return
-		}
-		convReq.Token = token
-	}
-
-	// Call conversion API
-	convertedURL, err := s.callConversionAPI(s.settings.DocumentServerURL, convReq, s.settings.DocumentServerSecret)
-	if err != nil {
-		log.Printf("Convert error: conversion failed: %v", err)
-		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Conversion failed: %v", err))
+		URL:        s.buildDownloadURL(filePath),
+		Password:   r.FormValue("password"),
+		CodePage:   textOpts.CodePage,
+		Delimiter:  textOpts.Delimiter,
+	})
+	if err == errConversionPassword {
+		log.Printf("Convert error: %s: %v", filePath, err)
+		s.respondFormError(w, r, http.StatusBadRequest, "密码错误，请重新输入文档密码")
 		return
 	}
-
-	// Download converted file
-	convertedContent, err := s.downloadConvertedFile(convertedURL)
 	if err != nil {
-		log.Printf("Convert error: failed to download converted file: %v", err)
-		s.respondError(w, http.StatusInternalServerError, "Failed to download converted file")
+		log.Printf("Convert error: %v", err)
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Conversion failed: %v", err))
 		return
 	}
 	defer convertedContent.Close()
 
-	// Build target file path
-	targetPath := s.buildTargetPath(filePath, targetFormat)
-
 	// Save converted file
 	if err := s.fileService.SaveFile(targetPath, convertedContent); err != nil {
 		log.Printf("Convert error: failed to save converted file: %v", err)
@@ -146,26 +167,80 @@ func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Conversion successful: %s -> %s", filePath, targetPath)
 
-	// For htmx requests, redirect to editor
+	// Archive or delete the original, the conversion is kept if this fails
+	archivedPath, err := s.handleOriginal(filePath, original)
+	if err != nil {
+		log.Printf("Convert error: failed to handle original %s: %v", filePath, err)
+	}
+
+	// For htmx requests, redirect to editor in the chosen open mode
 	if r.Header.Get("HX-Request") == "true" {
-		w.Header().Set("HX-Redirect", "/editor?path="+url.QueryEscape(targetPath))
+		editorURL := "/editor?path=" + url.QueryEscape(targetPath)
+		if mode := r.FormValue("mode"); policy.ValidMode(mode) {
+			editorURL += "&mode=" + mode
+		}
+		w.Header().Set("HX-Redirect", editorURL)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	// Return success with target path
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+	result := map[string]interface{}{
 		"success":    true,
 		"targetPath": targetPath,
 		"message":    "Conversion successful",
-	})
+	}
+	if archivedPath != "" {
+		result["archivedPath"] = archivedPath
+	}
+	s.respondJSON(w, http.StatusOK, result)
+}
+
+// convertDocument converts a document with the conversion API and returns
+// the converted content. The request is signed as a whole if JWT is enabled.
+func (s *Server) convertDocument(convReq *ConvertRequest) (io.ReadCloser, error) {
+	if s.settings == nil || s.settings.DocumentServerURL == "" {
+		return nil, fmt.Errorf("document server URL not configured")
+	}
+
+	// Sign request with JWT if secret is configured
+	if s.jwtEnabled() {
+		data, err := json.Marshal(convReq)
+		if err != nil {
+			return nil, err
+		}
+		var claims map[string]interface{}
+		if err := json.Unmarshal(data, &claims); err != nil {
+			return nil, err
+		}
+		token, err := s.signToken(claims)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign conversion request: %w", err)
+		}
+		convReq.Token = token
+	}
+
+	convertedURL, err := s.callConversionAPI(s.settings.DocumentServerURL, convReq, s.settings.DocumentServerSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.downloadConvertedFile(convertedURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download converted file: %w", err)
+	}
+	return content, nil
+}
+
+// conversionKey returns a new Document Server key for converting a file. The
+// path is hashed: keys only allow a few ASCII characters.
+func (s *Server) conversionKey(prefix, filePath string) string {
+	return prefix + "_" + s.configBuilder.GetDocumentKey(filePath, time.Now())
 }
 
 // buildDownloadURL builds the download URL for a file
 func (s *Server) buildDownloadURL(filePath string) string {
-	baseURL := s.getEffectiveBaseURL()
-	baseURL = strings.TrimSuffix(baseURL, "/")
-	return fmt.Sprintf("%s/download?path=%s", baseURL, url.QueryEscape(filePath))
+	return editor.DownloadURL(s.getEffectiveBaseURL(), filePath)
 }
 
 // getEffectiveBaseURL returns the effective base URL
@@ -214,7 +289,7 @@ func (s *Server) callConversionAPI(serverURL string, req *ConvertRequest, secret
 
 	// Add JWT token to header if configured
 	if secret != "" && req.Token != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+req.Token)
+		httpReq.Header.Set(s.settings.GetJWTHeader(), "Bearer "+req.Token)
 	}
 
 	// Send request
@@ -244,6 +319,9 @@ func (s *Server) callConversionAPI(serverURL string, req *ConvertRequest, secret
 		return "", fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	if convResp.Error == conversionErrorPassword {
+		return "", errConversionPassword
+	}
 	if convResp.Error != 0 {
 		return "", fmt.Errorf("conversion error code: %d", convResp.Error)
 	}
@@ -0,0 +1,506 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/convcache"
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/jwt"
+	"onlyoffice-fnos/internal/policy"
+)
+
+// Test password protected documents ask for the password and pass it to the conversion API
+func TestConvertEncrypted(t *testing.T) {
+	tempDir := t.TempDir()
+	encrypted, err := os.ReadFile(filepath.Join("testdata", "encrypted.doc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(tempDir, "secret.doc"), encrypted, 0644)
+
+	var conversion ConvertRequest
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			json.NewDecoder(r.Body).Decode(&conversion)
+			if conversion.Password != "open sesame" {
+				json.NewEncoder(w).Encode(&ConvertResponse{Error: conversionErrorPassword})
+				return
+			}
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/converted.docx"})
+			return
+		}
+		w.Write([]byte("converted docx"))
+	}))
+	defer mockDocServer.Close()
+
+	jwtManager := jwt.NewManager()
+	secret := jwtManager.GenerateSecret()
+	server := New(&Config{
+		Settings: &config.Settings{
+			DocumentServerURL:    mockDocServer.URL,
+			DocumentServerSecret: secret,
+		},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwtManager,
+		BaseURL:       "http://localhost:10099",
+	})
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/convert?path=secret.doc", nil))
+	if !strings.Contains(rec.Body.String(), `name="password"`) {
+		t.Errorf("Expected a password field on the convert page")
+	}
+
+	convert := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"path": {"secret.doc"}, "password": {password}}
+		req := httptest.NewRequest("POST", "/convert", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = convert("wrong")
+	if !strings.Contains(rec.Body.String(), "密码错误") || rec.Header().Get("HX-Redirect") != "" {
+		t.Errorf("Expected a password error, got %s", rec.Body.String())
+	}
+
+	rec = convert("open sesame")
+	if rec.Header().Get("HX-Redirect") != "/editor?path=secret.docx" {
+		t.Fatalf("Expected redirect to the converted document, got %q %s", rec.Header().Get("HX-Redirect"), rec.Body.String())
+	}
+	claims, err := jwtManager.Verify(secret, conversion.Token)
+	if err != nil || claims["password"] != "open sesame" || claims["outputtype"] != "docx" {
+		t.Errorf("Expected the password in the signed request, got %v %v", claims, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tempDir, "secret.docx")); string(content) != "converted docx" {
+		t.Errorf("Unexpected converted content %q", content)
+	}
+}
+
+// Test Markdown notes are edited in place on request and saved back as Markdown
+func TestRoundTripText(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "notes.md"), []byte("# Notes\n"), 0644)
+
+	var conversion ConvertRequest
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ConvertService.ashx":
+			json.NewDecoder(r.Body).Decode(&conversion)
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/converted.md"})
+		case "/converted.md":
+			w.Write([]byte("# Notes\n\nEdited\n"))
+		default:
+			w.Write([]byte("edited docx"))
+		}
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+	server.settings.BaseURL = "http://connector:10099"
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	if rec := get("/editor?path=notes.md&mode=edit"); rec.Code != http.StatusFound {
+		t.Errorf("Expected Markdown to be converted by default, got %d", rec.Code)
+	}
+	if rec := get("/convert?path=notes.md"); !strings.Contains(rec.Body.String(), "/editor?path=notes.md&amp;inplace=1&amp;mode=edit") {
+		t.Errorf("Expected the in-place option on the convert page")
+	}
+	rec := get("/editor?path=notes.md&inplace=1&mode=edit")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"fileType":"md"`) || !strings.Contains(rec.Body.String(), `"mode":"edit"`) {
+		t.Fatalf("Expected Markdown to open for editing, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `/callback?path=notes.md\u0026inplace=md`) {
+		t.Errorf("Expected the in-place format in the callback URL")
+	}
+
+	reqBody, _ := json.Marshal(&CallbackRequest{Key: "md-key", Status: StatusSaved, URL: mockDocServer.URL + "/edited.docx", Filetype: "docx"})
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("POST", "/callback?path=notes.md&inplace=md", bytes.NewReader(reqBody)))
+	if conversion.Filetype != "docx" || conversion.Outputtype != "md" {
+		t.Errorf("Unexpected conversion request %+v", conversion)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tempDir, "notes.md")); string(content) != "# Notes\n\nEdited\n" {
+		t.Errorf("Expected Markdown to be saved back, got %q", content)
+	}
+}
+
+// Test Markdown and HTML files open for viewing as their converted document
+func TestViewConverted(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "notes.md"), []byte("# Notes\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "page.htm"), []byte("<p>Page</p>"), 0644)
+
+	var conversion ConvertRequest
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			json.NewDecoder(r.Body).Decode(&conversion)
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/converted.docx"})
+			return
+		}
+		w.Write([]byte("converted docx"))
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+	server.settings.BaseURL = "http://connector:10099"
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	for _, name := range []string{"notes.md", "page.htm"} {
+		rec := get("/editor?path=" + name + "&mode=view")
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, `"fileType":"docx"`) || !strings.Contains(body, `"mode":"view"`) {
+			t.Fatalf("Expected %s to open for viewing as docx, got %d", name, rec.Code)
+		}
+		if !strings.Contains(body, `/download?path=`+name+`\u0026view=docx`) {
+			t.Errorf("Expected the converted download URL for %s", name)
+		}
+	}
+
+	rec := get("/download?path=notes.md&view=docx")
+	if rec.Code != http.StatusOK || rec.Body.String() != "converted docx" {
+		t.Fatalf("Expected the converted document, got %d %q", rec.Code, rec.Body.String())
+	}
+	if conversion.Filetype != "md" || conversion.Outputtype != "docx" {
+		t.Errorf("Unexpected conversion request %+v", conversion)
+	}
+	if rec := get("/download?path=notes.md&view=xlsx"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a format the file is not viewed as, got %d", rec.Code)
+	}
+}
+
+// Test GBK-encoded csv files are converted with the detected or chosen
+// encoding and delimiter, and saved back with them
+func TestConvertTextOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	// "姓名;年龄\n张三;30\n" in GBK
+	gbk := []byte("\xD0\xD5\xC3\xFB;\xC4\xEA\xC1\xE4\n\xD5\xC5\xC8\xFD;30\n")
+	os.WriteFile(filepath.Join(tempDir, "people.csv"), gbk, 0644)
+
+	var conversion ConvertRequest
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			json.NewDecoder(r.Body).Decode(&conversion)
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/converted"})
+			return
+		}
+		w.Write([]byte("converted"))
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+	server.settings.BaseURL = "http://connector:10099"
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+	convert := func(form url.Values) {
+		form.Set("conflict", "overwrite")
+		req := httptest.NewRequest("POST", "/convert", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Conversion failed: %d %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	body := get("/convert?path=people.csv").Body.String()
+	if !strings.Contains(body, `value="936" data-label="gbk" selected`) || !strings.Contains(body, `value="2" data-char=";" selected`) {
+		t.Errorf("Expected GBK and semicolon to be detected on the convert page")
+	}
+	if !strings.Contains(html.UnescapeString(body), base64.StdEncoding.EncodeToString(gbk)) {
+		t.Errorf("Expected the file sample for the preview")
+	}
+
+	convert(url.Values{"path": {"people.csv"}})
+	if conversion.CodePage != format.CodePageGBK || conversion.Delimiter != format.DelimiterSemicolon {
+		t.Errorf("Expected detected options in the conversion request, got %+v", conversion)
+	}
+	convert(url.Values{"path": {"people.csv"}, "codePage": {"65001"}, "delimiter": {"4"}})
+	if conversion.CodePage != format.CodePageUTF8 || conversion.Delimiter != format.DelimiterComma {
+		t.Errorf("Expected chosen options in the conversion request, got %+v", conversion)
+	}
+
+	// The encoding chosen for editing in place is used when saving back
+	rec := get("/editor?path=people.csv&inplace=1&mode=edit&codePage=1252&delimiter=1")
+	if !strings.Contains(rec.Body.String(), `/callback?path=people.csv\u0026codePage=1252\u0026delimiter=1\u0026inplace=csv`) {
+		t.Errorf("Expected the options in the callback URL, got %s", rec.Body.String())
+	}
+	save := func(callbackURL string) {
+		reqBody, _ := json.Marshal(&CallbackRequest{Key: "csv-key", Status: StatusSaved, URL: mockDocServer.URL + "/edited.xlsx", Filetype: "xlsx"})
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", callbackURL, bytes.NewReader(reqBody)))
+	}
+	save("/callback?path=people.csv&codePage=1252&delimiter=1&inplace=csv")
+	if conversion.Outputtype != "csv" || conversion.CodePage != format.CodePageWestern || conversion.Delimiter != format.DelimiterTab {
+		t.Errorf("Expected chosen options when saving back, got %+v", conversion)
+	}
+
+	// Without options the encoding of the replaced file is kept
+	os.WriteFile(filepath.Join(tempDir, "people.csv"), gbk, 0644)
+	save("/callback?path=people.csv&inplace=csv")
+	if conversion.CodePage != format.CodePageGBK || conversion.Delimiter != format.DelimiterSemicolon {
+		t.Errorf("Expected the original options when saving back, got %+v", conversion)
+	}
+}
+
+// Test conversions of the same content are reused and keyed by the content
+func TestConvertCache(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "report.doc"), []byte("legacy report"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "copy.doc"), []byte("legacy report"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "other.doc"), []byte("other report"), 0644)
+
+	var keys []string
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			var conversion ConvertRequest
+			json.NewDecoder(r.Body).Decode(&conversion)
+			keys = append(keys, conversion.Key)
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/converted.docx"})
+			return
+		}
+		w.Write([]byte("converted docx"))
+	}))
+	defer mockDocServer.Close()
+
+	server := New(&Config{
+		Settings:      &config.Settings{DocumentServerURL: mockDocServer.URL},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwt.NewManager(),
+		Conversions:   convcache.NewCache(t.TempDir(), 1<<20),
+		BaseURL:       "http://localhost:10099",
+	})
+
+	convert := func(path string) {
+		req := httptest.NewRequest("POST", "/convert?conflict=overwrite&path="+path, nil)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Conversion of %s failed: %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	convert("report.doc")
+	convert("report.doc")
+	convert("copy.doc")
+	if len(keys) != 1 {
+		t.Errorf("Expected one conversion for the same content, got %d", len(keys))
+	}
+	for _, name := range []string{"report.docx", "copy.docx"} {
+		if content, _ := os.ReadFile(filepath.Join(tempDir, name)); string(content) != "converted docx" {
+			t.Errorf("Expected %s from the cache, got %q", name, content)
+		}
+	}
+
+	convert("other.doc")
+	if len(keys) != 2 || keys[0] == keys[1] {
+		t.Errorf("Expected a new conversion with its own key, got %v", keys)
+	}
+
+	os.WriteFile(filepath.Join(tempDir, "report.doc"), []byte("edited report"), 0644)
+	convert("report.doc")
+	if len(keys) != 3 {
+		t.Errorf("Expected a new conversion for changed content, got %d", len(keys))
+	}
+
+	// Views of the same content reuse conversions too
+	os.WriteFile(filepath.Join(tempDir, "third.doc"), []byte("legacy report"), 0644)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/download?path=third.doc&view=docx", nil))
+	if rec.Code != http.StatusOK || len(keys) != 3 {
+		t.Errorf("Expected the view to reuse the conversion, got %d after %d conversions", rec.Code, len(keys))
+	}
+
+	// Uncached conversions hash the path into a valid Document Server key
+	os.MkdirAll(filepath.Join(tempDir, "年度 报告"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "年度 报告", "第一季度.doc"), []byte("protected report"), 0644)
+	convert(url.QueryEscape("年度 报告/第一季度.doc") + "&password=secret")
+	validKey := regexp.MustCompile(`^[0-9a-zA-Z._=-]{1,128}$`)
+	if len(keys) != 4 || !validKey.MatchString(keys[3]) {
+		t.Errorf("Expected a valid key for the uncached conversion, got %v", keys)
+	}
+}
+
+// Test existing targets are replaced or kept as chosen, and originals are archived or deleted
+func TestConvertTargetConflict(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{
+		"report.doc":  "legacy report",
+		"report.docx": "existing report",
+		"memo.doc":    "legacy memo",
+		"notes.doc":   "legacy notes",
+		"notes.docx":  "notes being edited",
+	} {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/converted.docx"})
+			return
+		}
+		w.Write([]byte("converted docx"))
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+
+	convert := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/convert", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	read := func(name string) string {
+		content, _ := os.ReadFile(filepath.Join(tempDir, name))
+		return string(content)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/convert?path=report.doc", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "目标文件 report.docx 已存在") || !strings.Contains(body, "另存为 report (converted).docx") {
+		t.Errorf("Expected the conflict choice on the convert page")
+	}
+
+	// By default the user is asked
+	rec = convert(url.Values{"path": {"report.doc"}})
+	if !strings.Contains(rec.Body.String(), "已存在") || rec.Header().Get("HX-Redirect") != "" || read("report.docx") != "existing report" {
+		t.Errorf("Expected the user to be asked, got %s", rec.Body.String())
+	}
+
+	rec = convert(url.Values{"path": {"report.doc"}, "conflict": {"rename"}})
+	if rec.Header().Get("HX-Redirect") != "/editor?path="+url.QueryEscape("report (converted).docx") {
+		t.Errorf("Expected the renamed conversion, got %q", rec.Header().Get("HX-Redirect"))
+	}
+	convert(url.Values{"path": {"report.doc"}, "conflict": {"rename"}})
+	if read("report (converted) (1).docx") != "converted docx" || read("report.docx") != "existing report" {
+		t.Errorf("Expected numbered names for repeated conversions")
+	}
+
+	convert(url.Values{"path": {"report.doc"}, "conflict": {"overwrite"}, "original": {"archive"}})
+	if read("report.docx") != "converted docx" {
+		t.Errorf("Expected the existing file to be replaced")
+	}
+	if read(filepath.Join("originals", "report.doc")) != "legacy report" || server.fileService.Exists("report.doc") {
+		t.Errorf("Expected the original to be archived")
+	}
+
+	convert(url.Values{"path": {"memo.doc"}, "original": {"delete"}})
+	if read("memo.docx") != "converted docx" || server.fileService.Exists("memo.doc") {
+		t.Errorf("Expected the original to be deleted")
+	}
+
+	// A document open in the editor is neither replaced nor moved
+	for key, path := range map[string]string{"notes-key": "notes.docx", "notes-doc-key": "notes.doc"} {
+		info, _ := server.fileService.GetFileInfo(path)
+		server.sessions.Register(key, path, info.ModTime)
+	}
+	convert(url.Values{"path": {"notes.doc"}, "conflict": {"overwrite"}, "original": {"delete"}})
+	if read("notes.docx") != "notes being edited" || read("notes (converted).docx") != "converted docx" || !server.fileService.Exists("notes.doc") {
+		t.Errorf("Expected open documents to be kept")
+	}
+
+	// The configured behavior applies when the user does not choose
+	server.settings.ConvertConflict = config.ConflictRename
+	rec = convert(url.Values{"path": {"notes.doc"}})
+	if rec.Header().Get("HX-Redirect") != "/editor?path="+url.QueryEscape("notes (converted) (1).docx") {
+		t.Errorf("Expected the configured rename, got %q %s", rec.Header().Get("HX-Redirect"), rec.Body.String())
+	}
+}
+
+// Test only users who may edit the original can archive or delete it, and deleted files are forgotten
+func TestConvertOriginalPermission(t *testing.T) {
+	tempDir := t.TempDir()
+	contracts := filepath.Join(tempDir, "vol1", "contracts")
+	os.MkdirAll(contracts, 0755)
+	os.WriteFile(filepath.Join(contracts, "b.doc"), []byte("legacy"), 0644)
+
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/converted.docx"})
+			return
+		}
+		w.Write([]byte("converted docx"))
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+	server.policy = &policy.Policy{Rules: []policy.Rule{
+		{Users: []string{"bob"}, Paths: []string{"/vol1/contracts/b.doc"}, Modes: []string{policy.ModeView}},
+	}}
+	const source = "/vol1/contracts/b.doc"
+
+	convert := func(user string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/convert?user_id="+user, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/convert?user_id=bob&path="+url.QueryEscape(source), nil))
+	if strings.Contains(rec.Body.String(), `value="delete"`) {
+		t.Errorf("Expected no delete choice for a read-only original")
+	}
+
+	rec = convert("bob", url.Values{"path": {source}, "original": {"delete"}})
+	if !strings.Contains(rec.Body.String(), "没有归档或删除原文件的权限") || rec.Header().Get("HX-Redirect") != "" || !server.fileService.Exists(source) {
+		t.Errorf("Expected the delete to be refused, got %s", rec.Body.String())
+	}
+
+	server.userdata.AddRecent("alice", source)
+	server.userdata.ToggleFavorite("alice", source)
+	id := server.fileids.ID(source)
+	rec = convert("alice", url.Values{"path": {source}, "conflict": {"overwrite"}, "original": {"delete"}})
+	if rec.Header().Get("HX-Redirect") == "" || server.fileService.Exists(source) {
+		t.Fatalf("Expected alice to delete the original, got %d %s", rec.Code, rec.Body.String())
+	}
+	if recent := server.userdata.Recent("alice"); len(recent) != 0 {
+		t.Errorf("Expected the deleted file to leave the recent list, got %v", recent)
+	}
+	if server.userdata.IsFavorite("alice", source) {
+		t.Errorf("Expected the deleted file to leave the favorites")
+	}
+	if _, ok := server.fileids.Path(id); ok {
+		t.Errorf("Expected the file ID of the deleted file to be removed")
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Editor types supported by the Document Server
+const (
+	editorTypeDesktop  = "desktop"
+	editorTypeMobile   = "mobile"
+	editorTypeEmbedded = "embedded"
+)
+
+// mobileUserAgents are User-Agent fragments of phones and tablets, including the fnOS iPad client
+var mobileUserAgents = []string{"Mobi", "Android", "iPhone", "iPad", "iPod", "HarmonyOS"}
+
+// editorType picks the editor type from the "type" query parameter, the
+// Sec-CH-UA-Mobile client hint or the User-Agent
+func editorType(r *http.Request) string {
+	switch t := r.URL.Query().Get("type"); t {
+	case editorTypeDesktop, editorTypeMobile, editorTypeEmbedded:
+		return t
+	}
+
+	if hint := r.Header.Get("Sec-CH-UA-Mobile"); hint != "" {
+		if hint == "?1" {
+			return editorTypeMobile
+		}
+		return editorTypeDesktop
+	}
+
+	ua := r.Header.Get("User-Agent")
+	for _, fragment := range mobileUserAgents {
+		if strings.Contains(ua, fragment) {
+			return editorTypeMobile
+		}
+	}
+	return editorTypeDesktop
+}
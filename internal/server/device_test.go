@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Unit test: Editor type follows the query override, client hints and User-Agent
+func TestEditorType(t *testing.T) {
+	tests := []struct {
+		name, query, hint, ua string
+		want                  string
+	}{
+		{"Desktop", "", "", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0", editorTypeDesktop},
+		{"iPhone", "", "", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148", editorTypeMobile},
+		{"iPad", "", "", "Mozilla/5.0 (iPad; CPU OS 16_0 like Mac OS X)", editorTypeMobile},
+		{"ClientHintMobile", "", "?1", "Mozilla/5.0 (Linux) Chrome/120.0", editorTypeMobile},
+		{"ClientHintDesktop", "", "?0", "Mozilla/5.0 (Linux; Android 14) Chrome/120.0", editorTypeDesktop},
+		{"Override", "?type=desktop", "", "Mozilla/5.0 (iPhone)", editorTypeDesktop},
+		{"InvalidOverride", "?type=tv", "", "Mozilla/5.0 (iPhone)", editorTypeMobile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/editor"+tt.query, nil)
+			req.Header.Set("User-Agent", tt.ua)
+			if tt.hint != "" {
+				req.Header.Set("Sec-CH-UA-Mobile", tt.hint)
+			}
+			if got := editorType(req); got != tt.want {
+				t.Errorf("editorType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test the embed route renders a read-only embedded editor
+func TestEmbedPage(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "a.docx"), []byte("doc"), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.settings.BaseURL = "http://connector:10099"
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/embed?mode=edit&user_id=alice&path="+url.QueryEscape("/vol1/a.docx"), nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `"type":"embedded"`) || !strings.Contains(body, `"mode":"view"`) {
+		t.Errorf("Expected read-only embedded config: %s", body)
+	}
+	if len(server.userdata.Recent("alice")) != 0 {
+		t.Errorf("Embedded previews should not be added to recent documents")
+	}
+	info, _ := server.fileService.GetFileInfo("/vol1/a.docx")
+	if _, ok := server.sessions.KeyForPath("/vol1/a.docx", info.ModTime); ok {
+		t.Errorf("Embedded previews should not open an editing session")
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"log"
 	"mime"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
 
 	"onlyoffice-fnos/internal/file"
+	jwtpkg "onlyoffice-fnos/internal/jwt"
 )
 
 // handleDownload handles GET /download
@@ -20,6 +24,15 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Verify the Document Server token if secret is configured
+	if s.jwtEnabled() {
+		if err := s.verifyDownload(r, filePath); err != nil {
+			log.Printf("Download error: invalid JWT token for %s: %v", filePath, err)
+			s.respondError(w, http.StatusForbidden, "Invalid token")
+			return
+		}
+	}
+
 	// Get file info
 	fileInfo, err := s.fileService.GetFileInfo(filePath)
 	if err != nil {
@@ -37,6 +50,12 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Files viewed through a conversion are served as the converted document
+	if view := r.URL.Query().Get("view"); view != "" {
+		s.serveConverted(w, filePath, fileInfo, view)
+		return
+	}
+
 	// Get file content
 	content, err := s.fileService.GetFileContent(filePath)
 	if err != nil {
@@ -54,7 +73,7 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 	defer content.Close()
 
 	// Set content type based on file extension
-	contentType := getContentType(fileInfo.Extension)
+	contentType := s.getContentType(fileInfo.Extension)
 	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Disposition", "attachment; filename=\""+fileInfo.Name+"\"")
 	w.Header().Set("Content-Length", formatInt64(fileInfo.Size))
@@ -66,26 +85,78 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// getContentType returns the MIME type for a file extension
-func getContentType(ext string) string {
-	// Map common Office extensions to their MIME types
-	mimeTypes := map[string]string{
-		"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
-		"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
-		"pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
-		"doc":  "application/msword",
-		"xls":  "application/vnd.ms-excel",
-		"ppt":  "application/vnd.ms-powerpoint",
-		"odt":  "application/vnd.oasis.opendocument.text",
-		"ods":  "application/vnd.oasis.opendocument.spreadsheet",
-		"odp":  "application/vnd.oasis.opendocument.presentation",
-		"pdf":  "application/pdf",
-		"rtf":  "application/rtf",
-		"txt":  "text/plain",
-		"csv":  "text/csv",
+// serveConverted streams the conversion of a file to its target format,
+// reusing an earlier conversion of the same content
+func (s *Server) serveConverted(w http.ResponseWriter, filePath string, fileInfo *file.FileInfo, target string) {
+	fileInfo, _ = s.sniffFileInfo(fileInfo)
+	if !s.formatManager.IsConvertible(fileInfo.Extension) || s.formatManager.GetConvertTarget(fileInfo.Extension) != target {
+		s.respondError(w, http.StatusBadRequest, "File is not viewed as "+target)
+		return
+	}
+
+	textOpts := s.textOptionsFor(nil, filePath, fileInfo.Extension)
+	content, err := s.convertCached(filePath, &ConvertRequest{
+		Filetype:   fileInfo.Extension,
+		Key:        s.conversionKey("view", filePath),
+		Outputtype: target,
+		Title:      fileInfo.Name,
+		URL:        s.buildDownloadURL(filePath),
+		CodePage:   textOpts.CodePage,
+		Delimiter:  textOpts.Delimiter,
+	})
+	if err != nil {
+		log.Printf("Download error: failed to convert %s to %s: %v", filePath, target, err)
+		s.respondError(w, http.StatusInternalServerError, "Conversion failed")
+		return
+	}
+	defer content.Close()
+
+	name := strings.TrimSuffix(fileInfo.Name, filepath.Ext(fileInfo.Name)) + "." + target
+	w.Header().Set("Content-Type", s.getContentType(target))
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+name+"\"")
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Error streaming conversion of %s: %v", filePath, err)
+	}
+}
+
+// verifyDownload accepts either a signed download URL issued for filePath
+// or a request signed by the Document Server for the URL of filePath
+func (s *Server) verifyDownload(r *http.Request, filePath string) error {
+	var claims map[string]interface{}
+	var err error
+	if queryToken := r.URL.Query().Get("token"); queryToken != "" {
+		claims, err = s.verifyToken(queryToken)
+	} else {
+		claims, err = s.verifyRequest(r, "")
+	}
+	if err != nil {
+		return err
 	}
+	if !claimsForPath(claims, filePath) {
+		return jwtpkg.ErrInvalidClaim
+	}
+	return nil
+}
 
-	if mimeType, ok := mimeTypes[ext]; ok {
+// claimsForPath returns true if verified claims were issued for filePath:
+// signed URLs carry the path, Document Server requests the URL they fetch.
+// Any other token, such as an editor configuration, is refused.
+func claimsForPath(claims map[string]interface{}, filePath string) bool {
+	path, ok := claims["path"].(string)
+	if !ok {
+		rawURL, _ := claims["url"].(string)
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return false
+		}
+		path = u.Query().Get("path")
+	}
+	return path != "" && filepath.Clean("/"+path) == filepath.Clean("/"+filePath)
+}
+
+// getContentType returns the MIME type for a file extension
+func (s *Server) getContentType(ext string) string {
+	if mimeType := s.formatManager.GetMIMEType(ext); mimeType != "" {
 		return mimeType
 	}
 
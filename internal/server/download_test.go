@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/jwt"
+)
+
+// Test download requires a valid Document Server token when JWT is enabled
+func TestDownloadWithJWTVerification(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "test.docx"), []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	jwtManager := jwt.NewManager()
+	secret := jwtManager.GenerateSecret()
+
+	server := New(&Config{
+		Settings: &config.Settings{
+			DocumentServerURL:    "http://example.com",
+			DocumentServerSecret: secret,
+		},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwtManager,
+		BaseURL:       "http://localhost:10099",
+	})
+
+	token, _ := jwtManager.Sign(secret, map[string]interface{}{
+		"payload": map[string]interface{}{"url": "http://localhost:10099/download?path=test.docx"},
+	})
+
+	testCases := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"NoToken", "", http.StatusForbidden},
+		{"InvalidToken", "Bearer invalid.token.value", http.StatusForbidden},
+		{"ValidToken", "Bearer " + token, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/download?path=test.docx", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d", tc.status, rec.Code)
+			}
+			if tc.status == http.StatusOK && rec.Body.String() != "content" {
+				t.Fatalf("Unexpected body %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+// Test tokens signed for one file are refused for another
+func TestDownloadTokenBoundToFile(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.docx"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "b.docx"), []byte("b"), 0644)
+
+	jwtManager := jwt.NewManager()
+	secret := jwtManager.GenerateSecret()
+	server := New(&Config{
+		Settings:      &config.Settings{DocumentServerURL: "http://example.com", DocumentServerSecret: secret},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwtManager,
+		BaseURL:       "http://localhost:10099",
+	})
+
+	forA, _ := jwtManager.Sign(secret, map[string]interface{}{
+		"payload": map[string]interface{}{"url": "http://localhost:10099/download?path=a.docx"},
+	})
+	signedA, _ := jwtManager.Sign(secret, map[string]interface{}{"path": "a.docx"})
+	// An editor configuration token is signed but names no file to download
+	config, _ := jwtManager.Sign(secret, map[string]interface{}{
+		"document": map[string]interface{}{"url": "http://localhost:10099/download?path=a.docx"},
+	})
+
+	testCases := []struct {
+		name   string
+		path   string
+		header string
+		status int
+	}{
+		{"HeaderForFile", "/download?path=a.docx", forA, http.StatusOK},
+		{"HeaderForOtherFile", "/download?path=b.docx", forA, http.StatusForbidden},
+		{"QueryForOtherFile", "/download?path=b.docx&token=" + signedA, "", http.StatusForbidden},
+		{"ThumbnailForOtherFile", "/thumbnail?path=b.docx", forA, http.StatusForbidden},
+		{"EditorConfig", "/download?path=a.docx", config, http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.header)
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d", tc.status, rec.Code)
+			}
+		})
+	}
+}
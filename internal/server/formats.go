@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+
+	"onlyoffice-fnos/internal/format"
+)
+
+// FormatsResponse is the response of GET /api/formats
+type FormatsResponse struct {
+	FileTypes []string         `json:"fileTypes"` // Extensions the connector opens, for the file manager integration
+	Formats   []*format.Format `json:"formats"`
+}
+
+// handleFormats handles GET /api/formats - lists the format table in effect
+func (s *Server) handleFormats(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, &FormatsResponse{
+		FileTypes: s.formatManager.Extensions(),
+		Formats:   s.formatManager.All(),
+	})
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"onlyoffice-fnos/internal/format"
+)
+
+// Test the format table in effect is published with overrides applied
+func TestFormatsAPI(t *testing.T) {
+	server := createTestServer(t, t.TempDir())
+	editable := true
+	if err := server.formatManager.Apply([]format.Override{{Extension: "odt", Editable: &editable}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/api/formats", nil))
+	if rec.Code != 200 {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var resp FormatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if len(resp.FileTypes) != len(resp.Formats) || resp.FileTypes[0] != "csv" {
+		t.Errorf("Expected sorted file types, got %v", resp.FileTypes)
+	}
+	for _, f := range resp.Formats {
+		if f.Extension == "odt" && (!f.Editable || f.Convertible) {
+			t.Errorf("Expected odt to be editable, got %+v", f)
+		}
+	}
+}
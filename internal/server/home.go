@@ -0,0 +1,304 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/userdata"
+	"onlyoffice-fnos/internal/users"
+)
+
+// maxSearchResults limits the number of files returned by a search
+const maxSearchResults = 200
+
+// User cookies remember the fnOS user passed by the desktop integration
+const (
+	userIDCookie   = "oo_user_id"
+	userNameCookie = "oo_user_name"
+)
+
+// The user of requests that do not name one
+const (
+	defaultUserID   = "fnos_user"
+	defaultUserName = "fnOS 用户"
+)
+
+// HomePageData holds data for the home page template
+type HomePageData struct {
+	UserName  string
+	Folder    string
+	Recent    []userdata.Entry
+	Favorites []*FavoriteEntry
+}
+
+// FavoriteEntry is a favorite document or folder shown on the home page
+type FavoriteEntry struct {
+	userdata.Entry
+	IsDir bool
+}
+
+// BrowseEntry is a folder entry shown in the file browser
+type BrowseEntry struct {
+	*file.FileInfo
+	Favorite     bool
+	Convertible  bool
+	Modes        []ModeOption
+	ThumbnailURL string
+}
+
+// SizeText returns the file size in a human readable form
+func (e *BrowseEntry) SizeText() string {
+	size := float64(e.Size)
+	for _, unit := range []string{"B", "KB", "MB", "GB"} {
+		if size < 1024 || unit == "GB" {
+			if unit == "B" {
+				return fmt.Sprintf("%d %s", e.Size, unit)
+			}
+			return fmt.Sprintf("%.1f %s", size, unit)
+		}
+		size /= 1024
+	}
+	return ""
+}
+
+// BrowsePageData holds data for the file browser fragment
+type BrowsePageData struct {
+	Folder  string
+	Parent  string
+	HasUp   bool
+	Query   string
+	Sort    string
+	Order   string
+	Entries []*BrowseEntry
+	WarmURL string // Renders the thumbnails of the folder in the background
+	Error   string
+}
+
+// handleHome handles GET / - renders the home page with recent documents,
+// favorites and the file browser
+func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
+	userID, userName := s.requestUser(w, r)
+
+	data := &HomePageData{
+		UserName: userName,
+		Folder:   r.URL.Query().Get("folder"),
+		Recent:   s.userdata.Recent(userID),
+	}
+	for _, entry := range s.userdata.Favorites(userID) {
+		if !s.canRead(userID, entry.Path) {
+			continue
+		}
+		data.Favorites = append(data.Favorites, &FavoriteEntry{
+			Entry: entry,
+			IsDir: s.fileService.CheckDir(entry.Path) == nil,
+		})
+	}
+
+	if s.templates == nil || s.templates.home == nil {
+		s.renderErrorPage(w, &ErrorPageData{
+			Title:   "渲染错误",
+			Message: "无法渲染主页",
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.home.Execute(w, data); err != nil {
+		log.Printf("Failed to render home template: %v", err)
+	}
+}
+
+// handleBrowse handles GET /browse - renders a folder listing or search results
+func (s *Server) handleBrowse(w http.ResponseWriter, r *http.Request) {
+	userID, _ := s.requestUser(w, r)
+
+	query := r.URL.Query()
+	data := &BrowsePageData{
+		Folder: query.Get("folder"),
+		Query:  strings.TrimSpace(query.Get("q")),
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+	}
+
+	var entries []*file.FileInfo
+	var err error
+	if data.Query != "" && data.Folder != "" {
+		entries, err = s.fileService.Search(data.Folder, data.Query, func(path, ext string) bool {
+			_, ok := s.formatManager.GetFormat(ext)
+			return ok && s.canRead(userID, path)
+		}, maxSearchResults)
+	} else {
+		data.Folder, entries, err = s.listFolder(data.Folder)
+	}
+	if err != nil {
+		log.Printf("Browse error: failed to list %s: %v", data.Folder, err)
+		data.Error = "文件夹不存在或无法访问"
+	}
+	if data.Folder != "" {
+		data.Parent, data.HasUp = s.folderParent(data.Folder)
+	}
+
+	for _, entry := range entries {
+		if data.Query != "" && !strings.Contains(strings.ToLower(entry.Name), strings.ToLower(data.Query)) {
+			continue
+		}
+		// Files and folders the policy hides are not listed
+		if !s.canRead(userID, entry.Path) {
+			continue
+		}
+		if !entry.IsDir {
+			if _, ok := s.formatManager.GetFormat(entry.Extension); !ok {
+				continue
+			}
+		}
+		data.Entries = append(data.Entries, &BrowseEntry{
+			FileInfo:    entry,
+			Favorite:    s.userdata.IsFavorite(userID, entry.Path),
+			Convertible: s.formatManager.IsConvertible(entry.Extension),
+		})
+		if !entry.IsDir {
+			data.Entries[len(data.Entries)-1].Modes = s.modeOptions(userID, entry.Path, entry.Extension)
+			data.Entries[len(data.Entries)-1].ThumbnailURL, _ = s.signedThumbnailURL("/thumbnail", "path", entry.Path, browseThumbnailSize)
+		}
+	}
+	sortEntries(data.Entries, data.Sort, data.Order == "desc")
+	if data.Folder != "" && data.Query == "" && err == nil {
+		data.WarmURL, _ = s.signedThumbnailURL("/thumbnail/warm", "folder", data.Folder, browseThumbnailSize)
+	}
+
+	if s.templates == nil || s.templates.browse == nil {
+		s.respondError(w, http.StatusInternalServerError, "Browser not available")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.browse.Execute(w, data); err != nil {
+		log.Printf("Failed to render browse template: %v", err)
+	}
+}
+
+// handleFavorite handles POST /favorites - toggles a favorite and returns the updated button
+func (s *Server) handleFavorite(w http.ResponseWriter, r *http.Request) {
+	userID, _ := s.requestUser(w, r)
+
+	path := r.FormValue("path")
+	if path == "" {
+		s.respondError(w, http.StatusBadRequest, "File path is required")
+		return
+	}
+	if err := s.fileService.CheckAccess(path); err != nil || !s.fileService.Exists(path) {
+		s.respondError(w, http.StatusNotFound, "File not found")
+		return
+	}
+	if !s.canRead(userID, path) {
+		log.Printf("Policy denies %s access to %s", userID, path)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	entry := &BrowseEntry{
+		FileInfo: &file.FileInfo{Path: path},
+		Favorite: s.userdata.ToggleFavorite(userID, path),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.browse.ExecuteTemplate(w, "favorite", entry); err != nil {
+		log.Printf("Failed to render favorite button: %v", err)
+	}
+}
+
+// requestUser returns the fnOS user of a request. The user passed in the query
+// by the desktop integration is remembered in cookies for later page loads.
+// If a trusted reverse proxy user header is configured, only the header is
+// trusted: the query and cookies can be forged by any client.
+func (s *Server) requestUser(w http.ResponseWriter, r *http.Request) (string, string) {
+	// A reverse proxy in front of the connector authenticates the user
+	if s.settings != nil && s.settings.UserHeader != "" {
+		// requireUser turns away requests without the header
+		userID := r.Header.Get(s.settings.UserHeader)
+		if userID == "" {
+			return "", ""
+		}
+		user := users.User{ID: userID}
+		if s.settings.UserNameHeader != "" {
+			user.Name = r.Header.Get(s.settings.UserNameHeader)
+		}
+		if s.settings.UserEmailHeader != "" {
+			user.Email = r.Header.Get(s.settings.UserEmailHeader)
+		}
+		s.users.Remember(user)
+		if user.Name == "" {
+			user.Name = userID
+		}
+		return user.ID, user.Name
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	userName := r.URL.Query().Get("user_name")
+
+	if userID != "" {
+		s.users.Remember(users.User{ID: userID, Name: userName})
+		http.SetCookie(w, &http.Cookie{Name: userIDCookie, Value: url.QueryEscape(userID), Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
+		if userName != "" {
+			http.SetCookie(w, &http.Cookie{Name: userNameCookie, Value: url.QueryEscape(userName), Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
+		}
+	} else if c, err := r.Cookie(userIDCookie); err == nil {
+		userID, _ = url.QueryUnescape(c.Value)
+		if userName == "" {
+			if c, err := r.Cookie(userNameCookie); err == nil {
+				userName, _ = url.QueryUnescape(c.Value)
+			}
+		}
+	}
+
+	if userID == "" {
+		userID = defaultUserID
+	}
+	if userName == "" {
+		userName = defaultUserName
+	}
+	return userID, userName
+}
+
+// authenticated returns false if a trusted user header is configured but
+// missing, the request did not come through the authenticating proxy
+func (s *Server) authenticated(r *http.Request) bool {
+	return s.settings == nil || s.settings.UserHeader == "" || r.Header.Get(s.settings.UserHeader) != ""
+}
+
+// requireUser rejects requests that did not come through the authenticating
+// proxy instead of serving them as the default user
+func (s *Server) requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authenticated(r) {
+			log.Printf("Rejected %s %s without the %s header", r.Method, r.URL.Path, s.settings.UserHeader)
+			s.respondError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sortEntries sorts folders first, then by name, modification time or size
+func sortEntries(entries []*BrowseEntry, by string, desc bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if desc {
+			a, b = b, a
+		}
+		switch by {
+		case "modified":
+			return a.ModTime.Before(b.ModTime)
+		case "size":
+			return a.Size < b.Size
+		default:
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"onlyoffice-fnos/internal/policy"
+)
+
+// Test the browser lists supported documents and searches subfolders
+func TestBrowse(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1", "docs", "2024"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "docs", "report.docx"), []byte("doc"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "docs", "budget.xls"), []byte("xls"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "docs", "video.mp4"), []byte("mp4"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "docs", "2024", "report-2024.pptx"), []byte("ppt"), 0644)
+
+	server := createTestServer(t, tempDir)
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	body := get("/browse?folder=" + url.QueryEscape("/vol1/docs"))
+	if !strings.Contains(body, "report.docx") || !strings.Contains(body, "2024") {
+		t.Errorf("Expected documents and folders in listing: %s", body)
+	}
+	if strings.Contains(body, "video.mp4") {
+		t.Errorf("Unsupported files should be hidden")
+	}
+	if !strings.Contains(body, "/convert?path=%2fvol1%2fdocs%2fbudget.xls") {
+		t.Errorf("Expected convert action for xls: %s", body)
+	}
+	if strings.Index(body, "budget.xls") > strings.Index(body, "report.docx") {
+		t.Errorf("Expected entries sorted by name")
+	}
+	if body = get("/browse?folder=" + url.QueryEscape("/vol1/docs") + "&sort=name&order=desc"); strings.Index(body, "budget.xls") < strings.Index(body, "report.docx") {
+		t.Errorf("Expected entries sorted by name descending")
+	}
+
+	body = get("/browse?folder=" + url.QueryEscape("/vol1") + "&q=REPORT")
+	if !strings.Contains(body, "report.docx") || !strings.Contains(body, "report-2024.pptx") || strings.Contains(body, "budget.xls") {
+		t.Errorf("Unexpected search results: %s", body)
+	}
+
+	// Entries the policy hides from a user are not listed
+	server.policy = &policy.Policy{Rules: []policy.Rule{
+		{Users: []string{"bob"}, Paths: []string{"/vol1/docs/2024", "/vol1/docs/report.docx"}, Modes: []string{}},
+	}}
+	body = get("/browse?user_id=bob&folder=" + url.QueryEscape("/vol1/docs"))
+	if strings.Contains(body, "report.docx") || strings.Contains(body, "2024") || !strings.Contains(body, "budget.xls") {
+		t.Errorf("Expected hidden entries to be left out: %s", body)
+	}
+	if body = get("/browse?user_id=bob&folder=" + url.QueryEscape("/vol1") + "&q=report"); strings.Contains(body, "report.docx") || strings.Contains(body, "report-2024.pptx") {
+		t.Errorf("Expected hidden entries to be left out of search results: %s", body)
+	}
+
+	form := url.Values{"path": {"/vol1/docs/report.docx"}}
+	req := httptest.NewRequest("POST", "/favorites?user_id=bob", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || server.userdata.IsFavorite("bob", "/vol1/docs/report.docx") {
+		t.Errorf("Expected 403 for pinning a hidden file, got %d", rec.Code)
+	}
+}
+
+// Test favorites and recent documents are kept per user
+func TestHomeFavorites(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "plan.docx"), []byte("doc"), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.userdata.AddRecent("alice", "/vol1/recent.xlsx")
+
+	form := url.Values{"path": {"/vol1/plan.docx"}}
+	req := httptest.NewRequest("POST", "/favorites?user_id=alice", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "★") {
+		t.Fatalf("Expected favorite button, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The user is remembered in a cookie
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == userIDCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("Expected user cookie")
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, "plan.docx") || !strings.Contains(body, "recent.xlsx") {
+		t.Errorf("Expected favorite and recent document on home page: %s", body)
+	}
+
+	// Favorite folders open in the browser, not in the editor
+	server.userdata.ToggleFavorite("alice", "/vol1")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/?user_id=alice", nil))
+	if body = rec.Body.String(); !strings.Contains(body, `href="/?folder=%2fvol1"`) || strings.Contains(body, `href="/editor?path=%2fvol1"`) {
+		t.Errorf("Expected favorite folder to link to the folder view: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/?user_id=bob", nil))
+	if strings.Contains(rec.Body.String(), "plan.docx") {
+		t.Errorf("Favorites should not be shared between users")
+	}
+}
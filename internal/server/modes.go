@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/url"
+
+	"onlyoffice-fnos/internal/editor"
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/policy"
+)
+
+// ModeOption is an open mode offered on the browser and convert pages
+type ModeOption struct {
+	Mode  string
+	Label string
+	URL   string
+}
+
+// readModes are all open modes, any of them lets a user read a file
+var readModes = []string{policy.ModeEdit, policy.ModeStrict, policy.ModeReview, policy.ModeComment, policy.ModeFillForms, policy.ModeView}
+
+// writeModes are the open modes that change a file freely. Creating,
+// renaming, replacing and removing files requires one of them.
+var writeModes = []string{policy.ModeEdit, policy.ModeStrict}
+
+// resolveMode picks the open mode for a user from the requested mode, the
+// format and the permission policy. It returns false if the policy denies access.
+func (s *Server) resolveMode(userID, filePath, requested string, f *format.Format) (string, []string, bool) {
+	return s.policy.Resolve(userID, filePath, requested, editor.FormatModes(f))
+}
+
+// modeOptions returns the open modes a user may choose for a file
+func (s *Server) modeOptions(userID, filePath, ext string) []ModeOption {
+	f, ok := s.formatManager.GetFormat(ext)
+	if !ok {
+		return nil
+	}
+	_, allowed, ok := s.resolveMode(userID, filePath, "", f)
+	if !ok {
+		return nil
+	}
+
+	options := make([]ModeOption, 0, len(allowed))
+	for _, mode := range allowed {
+		options = append(options, ModeOption{
+			Mode:  mode,
+			Label: policy.ModeLabel(mode),
+			URL:   "/editor?path=" + url.QueryEscape(filePath) + "&mode=" + mode,
+		})
+	}
+	return options
+}
+
+// canRead returns true if the permission policy lets a user open a file in any mode
+func (s *Server) canRead(userID, filePath string) bool {
+	_, _, ok := s.policy.Resolve(userID, filePath, "", readModes)
+	return ok
+}
+
+// canWrite returns true if the permission policy lets a user edit a file, or
+// create one at filePath
+func (s *Server) canWrite(userID, filePath string) bool {
+	_, _, ok := s.policy.Resolve(userID, filePath, "", writeModes)
+	return ok
+}
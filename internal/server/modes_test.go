@@ -0,0 +1,225 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"onlyoffice-fnos/internal/policy"
+)
+
+// Test open modes translate into permissions and coEditing settings
+func TestEditorConfigModes(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "a.docx"), []byte("doc"), 0644)
+
+	server := createTestServer(t, tempDir)
+	fileInfo, _ := server.fileService.GetFileInfo("/vol1/a.docx")
+
+	tests := []struct {
+		mode       string
+		editorMode string
+		permission string // Permission that must be granted
+		edit       bool
+		coEditing  string
+	}{
+		{policy.ModeEdit, "edit", "edit", true, "fast"},
+		{policy.ModeStrict, "edit", "edit", true, "strict"},
+		{policy.ModeReview, "edit", "review", false, ""},
+		{policy.ModeComment, "edit", "comment", false, ""},
+		{policy.ModeFillForms, "edit", "fillForms", false, ""},
+		{policy.ModeView, "view", "download", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg, err := server.buildEditorConfig(&editorConfigRequest{
+				FilePath: "/vol1/a.docx",
+				FileInfo: fileInfo,
+				UserID:   "alice",
+				Mode:     tt.mode,
+			})
+			if err != nil {
+				t.Fatalf("Failed to build config: %v", err)
+			}
+
+			data, _ := json.Marshal(cfg.Document.Permissions)
+			var permissions map[string]interface{}
+			json.Unmarshal(data, &permissions)
+			if cfg.EditorConfig.Mode != tt.editorMode || permissions[tt.permission] != true || permissions["edit"] != tt.edit {
+				t.Errorf("Unexpected mode %v with permissions %v", cfg.EditorConfig.Mode, permissions)
+			}
+
+			coEditing := cfg.EditorConfig.CoEditing
+			if tt.coEditing == "" && coEditing != nil {
+				t.Errorf("Unexpected coEditing %v", coEditing)
+			}
+			if tt.coEditing != "" && (coEditing == nil || coEditing.Mode != tt.coEditing) {
+				t.Errorf("Expected coEditing mode %s, got %v", tt.coEditing, coEditing)
+			}
+		})
+	}
+}
+
+// Test the policy restricts modes on the editor and browser pages
+func TestEditorPagePolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1", "contracts"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "contracts", "a.docx"), []byte("doc"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "contracts", "b.oform"), []byte("form"), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.settings.BaseURL = "http://connector:10099"
+	server.policy = &policy.Policy{Rules: []policy.Rule{
+		{Users: []string{"bob"}, Paths: []string{"/vol1/contracts"}, Modes: []string{policy.ModeComment, policy.ModeView}},
+		{Users: []string{"carol"}, Paths: []string{"/vol1/contracts"}, Modes: []string{}},
+	}}
+	server.sessions.Register("a-key", "/vol1/contracts/a.docx", time.Now())
+
+	open := func(path, query string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/editor?path="+url.QueryEscape(path)+query, nil))
+		return rec.Body.String()
+	}
+
+	body := open("/vol1/contracts/a.docx", "&user_id=bob&mode=edit")
+	if !strings.Contains(body, `"comment":true`) || strings.Contains(body, `"edit":true`) {
+		t.Errorf("Expected comment-only permissions for bob: %s", body)
+	}
+
+	// Form filling is not allowed for bob, the form opens read-only
+	if body = open("/vol1/contracts/b.oform", "&user_id=bob"); !strings.Contains(body, `"mode":"view"`) {
+		t.Errorf("Expected read-only form for bob: %s", body)
+	}
+
+	if body = open("/vol1/contracts/a.docx", "&user_id=carol"); !strings.Contains(body, "权限不足") {
+		t.Errorf("Expected policy denial for carol")
+	}
+
+	if body = open("/vol1/contracts/b.oform", "&user_id=alice"); !strings.Contains(body, `"fillForms":true`) {
+		t.Errorf("Expected forms to open in fill mode: %s", body)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/browse?user_id=bob&folder="+url.QueryEscape("/vol1/contracts"), nil))
+	if body = rec.Body.String(); strings.Contains(body, "严格协作编辑") || !strings.Contains(body, "仅批注") {
+		t.Errorf("Expected only allowed modes in browser: %s", body)
+	}
+}
+
+// Test handlers that create, change or hand out files enforce the policy
+func TestHandlersPolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	contracts := filepath.Join(tempDir, "vol1", "contracts")
+	os.MkdirAll(contracts, 0755)
+	os.WriteFile(filepath.Join(contracts, "a.docx"), []byte("doc"), 0644)
+	os.WriteFile(filepath.Join(contracts, "b.doc"), []byte("legacy"), 0644)
+	os.WriteFile(filepath.Join(contracts, "c.png"), []byte("png"), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.policy = &policy.Policy{Rules: []policy.Rule{
+		{Users: []string{"bob"}, Paths: []string{"/vol1/contracts"}, Modes: []string{policy.ModeComment, policy.ModeView}},
+		{Users: []string{"carol"}, Paths: []string{"/vol1/contracts"}, Modes: []string{}},
+	}}
+	server.sessions.Register("a-key", "/vol1/contracts/a.docx", time.Time{})
+
+	send := func(method, target, contentType, body string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	const form = "application/x-www-form-urlencoded"
+
+	tests := []struct {
+		name, method, target, contentType, body string
+	}{
+		{"rename", "POST", "/rename?user_id=bob", "", `{"key": "a-key", "title": "b.docx"}`},
+		{"save as", "POST", "/saveas?user_id=bob", "", `{"path": "/vol1/contracts/a.docx", "title": "copy", "fileType": "docx", "url": "http://example.com/cache/a.docx"}`},
+		{"new", "POST", "/new?user_id=bob", form, "folder=" + url.QueryEscape("/vol1/contracts") + "&type=word"},
+		{"convert target", "POST", "/convert?user_id=bob", form, "path=" + url.QueryEscape("/vol1/contracts/b.doc")},
+		{"convert source", "POST", "/convert?user_id=carol", form, "path=" + url.QueryEscape("/vol1/contracts/b.doc")},
+		{"picker", "POST", "/picker/select?user_id=carol", "", `{"type": "image", "path": "/vol1/contracts/c.png", "c": "add", "key": "a-key"}`},
+		{"thumbnail", "GET", "/thumbnail?user_id=carol&path=" + url.QueryEscape("/vol1/contracts/a.docx"), "", ""},
+	}
+	for _, tt := range tests {
+		if code := send(tt.method, tt.target, tt.contentType, tt.body); code != 403 {
+			t.Errorf("%s: expected 403, got %d", tt.name, code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(contracts, "a.docx")); err != nil {
+		t.Errorf("Denied rename should keep the file: %v", err)
+	}
+
+	if code := send("POST", "/rename?user_id=alice", "", `{"key": "a-key", "title": "b.docx"}`); code != 200 {
+		t.Errorf("Expected alice to rename, got %d", code)
+	}
+}
+
+// Test only the trusted header names the user once it is configured
+func TestRequestUserTrustedHeader(t *testing.T) {
+	server := createTestServer(t, t.TempDir())
+
+	req := httptest.NewRequest("GET", "/?user_id=bob", nil)
+	if userID, _ := server.requestUser(httptest.NewRecorder(), req); userID != "bob" {
+		t.Errorf("Expected the query user without a header, got %s", userID)
+	}
+
+	server.settings.UserHeader = "X-Forwarded-User"
+	req.AddCookie(&http.Cookie{Name: userIDCookie, Value: "bob"})
+	if userID, _ := server.requestUser(httptest.NewRecorder(), req); userID != "" {
+		t.Errorf("Expected query and cookie to be ignored, got %s", userID)
+	}
+	req.Header.Set("X-Forwarded-User", "alice")
+	if userID, _ := server.requestUser(httptest.NewRecorder(), req); userID != "alice" {
+		t.Errorf("Expected the header user, got %s", userID)
+	}
+}
+
+// Test requests bypassing the trusted proxy are rejected, not served as the default user
+func TestRequireUserHeader(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.docx"), []byte("content"), 0644)
+	server := createTestServer(t, tempDir)
+	server.settings.UserHeader = "X-Forwarded-User"
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/", nil),
+		httptest.NewRequest("GET", "/editor?path=a.docx&user_id="+defaultUserID, nil),
+		httptest.NewRequest("GET", "/convert?path=a.docx", nil),
+		httptest.NewRequest("POST", "/new", strings.NewReader("folder=/&type=docx&name=b")),
+		httptest.NewRequest("POST", "/rename", strings.NewReader(`{"key": "k", "title": "b.docx"}`)),
+	}
+	for _, req := range requests {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %s %s without the header, got %d", req.Method, req.URL, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the home page with the header, got %d", rec.Code)
+	}
+
+	// The Document Server fetches files without going through the proxy
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/download?path=a.docx", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected downloads to skip the header check, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"onlyoffice-fnos/internal/editor"
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/newdoc"
+)
+
+// NewPageData holds data for the new document page template
+type NewPageData struct {
+	Folder    string
+	Kind      string
+	Name      string
+	Template  string
+	Kinds     []*newdoc.Kind
+	Templates []*newdoc.Template
+	Error     string
+}
+
+// handleNewPage handles GET /new - renders the new document form
+func (s *Server) handleNewPage(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+	if folder == "" {
+		s.renderErrorPage(w, &ErrorPageData{
+			Title:   "参数错误",
+			Message: "未指定目标文件夹",
+		})
+		return
+	}
+
+	kind := r.URL.Query().Get("type")
+	if _, err := newdoc.GetKind(kind); err != nil {
+		kind = newdoc.KindWord
+	}
+
+	templates, err := newdoc.ListTemplates(s.templatesDir(), "")
+	if err != nil {
+		log.Printf("Failed to list templates: %v", err)
+	}
+
+	data := &NewPageData{
+		Folder:    folder,
+		Kind:      kind,
+		Template:  r.URL.Query().Get("template"),
+		Kinds:     newdoc.Kinds(),
+		Templates: templates,
+	}
+	if err := s.fileService.CheckDir(folder); err != nil {
+		data.Error = "目标文件夹不存在或无法访问"
+	}
+
+	if s.templates != nil && s.templates.newDoc != nil {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := s.templates.newDoc.Execute(w, data); err != nil {
+			log.Printf("Failed to render new document template: %v", err)
+			s.renderErrorPage(w, &ErrorPageData{
+				Title:   "渲染错误",
+				Message: "无法渲染新建页面",
+			})
+		}
+		return
+	}
+
+	s.renderErrorPage(w, &ErrorPageData{
+		Title:   "渲染错误",
+		Message: "无法渲染新建页面",
+	})
+}
+
+// handleNewDocument handles POST /new - creates a blank document or a copy of a template
+func (s *Server) handleNewDocument(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.respondFormError(w, r, http.StatusBadRequest, "无效的请求")
+		return
+	}
+
+	folder := r.FormValue("folder")
+	if folder == "" {
+		s.respondFormError(w, r, http.StatusBadRequest, "未指定目标文件夹")
+		return
+	}
+	if err := s.fileService.CheckDir(folder); err != nil {
+		log.Printf("New document error: invalid folder %s: %v", folder, err)
+		s.respondFormError(w, r, http.StatusBadRequest, "目标文件夹不存在或无法访问")
+		return
+	}
+
+	// Determine the document kind and its initial content
+	var kind *newdoc.Kind
+	var content io.Reader
+	if templatePath := r.FormValue("template"); templatePath != "" {
+		rc, tmpl, err := newdoc.OpenTemplate(s.templatesDir(), templatePath)
+		if err != nil {
+			log.Printf("New document error: template %s: %v", templatePath, err)
+			s.respondFormError(w, r, http.StatusBadRequest, "模板不存在")
+			return
+		}
+		defer rc.Close()
+		kind, _ = newdoc.GetKind(tmpl.Kind)
+		content = rc
+	} else {
+		var err error
+		kind, err = newdoc.GetKind(r.FormValue("type"))
+		if err != nil {
+			s.respondFormError(w, r, http.StatusBadRequest, "不支持的文档类型")
+			return
+		}
+		blank, err := kind.Blank()
+		if err != nil {
+			log.Printf("New document error: failed to build blank %s: %v", kind.Name, err)
+			s.respondFormError(w, r, http.StatusInternalServerError, "无法生成空白文档")
+			return
+		}
+		content = bytes.NewReader(blank)
+	}
+
+	name, ok := newDocumentName(r.FormValue("name"), kind)
+	if !ok {
+		s.respondFormError(w, r, http.StatusBadRequest, "文件名无效")
+		return
+	}
+
+	if userID, _ := s.requestUser(w, r); !s.canWrite(userID, filepath.Join(folder, name)) {
+		log.Printf("Policy denies %s creating %s in %s", userID, name, folder)
+		s.respondFormError(w, r, http.StatusForbidden, "没有写入权限")
+		return
+	}
+
+	targetPath, err := s.fileService.UniquePath(filepath.Join(folder, name))
+	if err != nil {
+		s.respondFormError(w, r, http.StatusBadRequest, "无法确定文件名")
+		return
+	}
+
+	if err := s.fileService.SaveFile(targetPath, content); err != nil {
+		log.Printf("New document error: failed to save %s: %v", targetPath, err)
+		msg := "无法保存文件"
+		if err == file.ErrPermissionDenied {
+			msg = "没有写入权限"
+		}
+		s.respondFormError(w, r, http.StatusInternalServerError, msg)
+		return
+	}
+
+	log.Printf("Created new document %s", targetPath)
+
+	editorURL := "/editor?path=" + url.QueryEscape(targetPath)
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", editorURL)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, editorURL, http.StatusSeeOther)
+}
+
+// newDocumentName validates a user supplied file name and adds the kind's extension
+func newDocumentName(name string, kind *newdoc.Kind) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = kind.DefaultName
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+		return "", false
+	}
+	if !strings.EqualFold(filepath.Ext(name), "."+kind.Extension) {
+		name += "." + kind.Extension
+	}
+	return name, true
+}
+
+// templatesDir returns the user template folder, empty if not configured
+func (s *Server) templatesDir() string {
+	if s.settings == nil {
+		return ""
+	}
+	return s.settings.TemplatesDir
+}
+
+// newDocumentURL builds the relative URL of the new document page
+func newDocumentURL(folder, kind, templatePath string) string {
+	query := url.Values{}
+	query.Set("folder", folder)
+	query.Set("type", kind)
+	if templatePath != "" {
+		query.Set("template", templatePath)
+	}
+	return "/new?" + query.Encode()
+}
+
+// editorTemplates builds the editorConfig.templates list for the "Create New" menu
+func (s *Server) editorTemplates(folder, kind string) []editor.TemplateConfig {
+	result := []editor.TemplateConfig{
+		{Title: "空白", URL: newDocumentURL(folder, kind, "")},
+	}
+
+	templates, err := newdoc.ListTemplates(s.templatesDir(), kind)
+	if err != nil {
+		log.Printf("Failed to list templates: %v", err)
+	}
+	for _, t := range templates {
+		result = append(result, editor.TemplateConfig{
+			Title: t.Name,
+			URL:   newDocumentURL(folder, kind, t.Path),
+		})
+	}
+	return result
+}
+
+// respondFormError reports a form error as an htmx fragment or JSON
+func (s *Server) respondFormError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if r.Header.Get("HX-Request") == "true" {
+		// htmx only swaps successful responses
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`<div class="notification is-danger">` + template.HTMLEscapeString(message) + `</div>`))
+		return
+	}
+	s.respondError(w, status, message)
+}
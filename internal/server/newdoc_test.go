@@ -0,0 +1,61 @@
+package server
+
+import (
+	"archive/zip"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test creating blank documents with collision-safe names
+func TestNewDocument(t *testing.T) {
+	tempDir := t.TempDir()
+	server := createTestServer(t, tempDir)
+
+	create := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/new", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	form := url.Values{"folder": {"/"}, "name": {"Report"}, "type": {"word"}}
+
+	rec := create(form)
+	if got := rec.Header().Get("HX-Redirect"); got != "/editor?path="+url.QueryEscape("/Report.docx") {
+		t.Fatalf("Unexpected redirect %q, body %s", got, rec.Body.String())
+	}
+	if _, err := zip.OpenReader(filepath.Join(tempDir, "Report.docx")); err != nil {
+		t.Fatalf("Created document is not a valid package: %v", err)
+	}
+
+	// A second document with the same name must not overwrite the first
+	rec = create(form)
+	if got := rec.Header().Get("HX-Redirect"); got != "/editor?path="+url.QueryEscape("/Report (1).docx") {
+		t.Fatalf("Unexpected redirect for duplicate name %q", got)
+	}
+
+	// Default names and other kinds
+	rec = create(url.Values{"folder": {"/"}, "type": {"cell"}})
+	if _, err := os.Stat(filepath.Join(tempDir, "新建表格.xlsx")); err != nil {
+		t.Fatalf("Spreadsheet with default name not created: %v", err)
+	}
+
+	// Invalid input is reported as an htmx fragment
+	for _, bad := range []url.Values{
+		{"folder": {"/missing"}, "type": {"word"}},
+		{"folder": {"/"}, "type": {"video"}},
+		{"folder": {"/"}, "type": {"word"}, "name": {"../escape"}},
+	} {
+		rec = create(bad)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "is-danger") || rec.Header().Get("HX-Redirect") != "" {
+			t.Fatalf("Expected error fragment for %v, got %d %q", bad, rec.Code, rec.Body.String())
+		}
+	}
+}
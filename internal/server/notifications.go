@@ -0,0 +1,133 @@
+package server
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"onlyoffice-fnos/internal/editor"
+	"onlyoffice-fnos/internal/notify"
+)
+
+// reportSaveError records a failed save reported by the Document Server.
+// If the callback carries a document URL, the last version is kept as a recovery copy.
+func (s *Server) reportSaveError(filePath string, req *CallbackRequest) {
+	if s.notifier == nil {
+		return
+	}
+
+	kind := notify.KindSaveError
+	if req.Status == StatusForceSaveError {
+		kind = notify.KindForceSaveError
+	}
+
+	ev := &notify.Event{
+		Kind:  kind,
+		Path:  filePath,
+		Key:   req.Key,
+		Users: req.Users,
+	}
+
+	var recovered io.Reader
+	if req.URL != "" {
+		client := &http.Client{Timeout: 5 * time.Minute}
+		resp, err := client.Get(req.URL)
+		if err != nil {
+			log.Printf("Failed to fetch recovery copy for %s: %v", filePath, err)
+		} else {
+			defer resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				recovered = resp.Body
+			} else {
+				log.Printf("Failed to fetch recovery copy for %s: status %d", filePath, resp.StatusCode)
+			}
+		}
+	}
+
+	if err := s.notifier.Report(ev, recovered); err != nil {
+		log.Printf("Failed to record save error for %s: %v", filePath, err)
+	}
+}
+
+// pendingNotices returns the unacknowledged notifications of a document for a user
+func (s *Server) pendingNotices(filePath, userID string) []*notify.Event {
+	if s.notifier == nil {
+		return nil
+	}
+	return s.notifier.PendingFor(filePath, userID)
+}
+
+// handleRecoveryDownload handles GET /recovery - downloads a recovered document copy
+func (s *Server) handleRecoveryDownload(w http.ResponseWriter, r *http.Request) {
+	if s.notifier == nil {
+		s.respondError(w, http.StatusNotFound, "Notifications are not enabled")
+		return
+	}
+
+	ev, err := s.notifier.Get(r.URL.Query().Get("id"))
+	if err != nil || !ev.HasRecovery() {
+		s.respondError(w, http.StatusNotFound, "Recovery copy not found")
+		return
+	}
+	if userID, _ := s.requestUser(w, r); !ev.ShownTo(userID) || !s.canRead(userID, ev.Path) {
+		log.Printf("Policy denies %s the recovery copy of %s", userID, ev.Path)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	content, err := s.notifier.OpenRecovery(ev)
+	if err != nil {
+		log.Printf("Failed to open recovery copy %s: %v", ev.RecoveryFile, err)
+		s.respondError(w, http.StatusNotFound, "Recovery copy not found")
+		return
+	}
+	defer content.Close()
+
+	name := filepath.Base(ev.Path)
+	w.Header().Set("Content-Type", s.getContentType(editor.GetFileExtension(name)))
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+name+"\"")
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Error streaming recovery copy %s: %v", ev.RecoveryFile, err)
+	}
+}
+
+// handleNotificationAck handles POST /notifications/ack - dismisses a notification
+func (s *Server) handleNotificationAck(w http.ResponseWriter, r *http.Request) {
+	if s.notifier == nil {
+		s.respondError(w, http.StatusNotFound, "Notifications are not enabled")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		if err := r.ParseForm(); err == nil {
+			id = r.FormValue("id")
+		}
+	}
+
+	// Only users the notice is shown to may dismiss it
+	ev, err := s.notifier.Get(id)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+	if userID, _ := s.requestUser(w, r); !ev.ShownTo(userID) || !s.canRead(userID, ev.Path) {
+		log.Printf("Policy denies %s dismissing notification %s", userID, id)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	if err := s.notifier.Acknowledge(id); err != nil {
+		s.respondError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+
+	// htmx swaps the banner with the (empty) response
+	if r.Header.Get("HX-Request") == "true" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
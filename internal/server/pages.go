@@ -7,19 +7,28 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strings"
 
+	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/editor"
 	"onlyoffice-fnos/internal/file"
 	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/newdoc"
+	"onlyoffice-fnos/internal/notify"
+	"onlyoffice-fnos/internal/policy"
 	"onlyoffice-fnos/web"
 )
 
 // EditorPageData holds data for the editor page template
 type EditorPageData struct {
 	Title         string
+	FilePath      string
 	ConfigJSON    template.JS
 	DocServerPath string // Frontend path for loading JS (e.g., "/doc-svr")
 	Lang          string
+	Notices       []*notify.Event // Unacknowledged save errors for this document
+	Embedded      bool            // Read-only preview inside an iframe
 }
 
 // ConvertPageData holds data for the convert page template
@@ -30,6 +39,19 @@ type ConvertPageData struct {
 	SourceFormat    string
 	TargetFormat    string
 	CanDirectEdit   bool
+	Modes           []ModeOption // Open modes for the converted document
+	Warning         string       // Shown when the extension does not match the content
+	Encrypted       bool         // The document is password protected
+	InPlaceURL      string       // Edits the document in its own format, empty if not supported
+	Text            *TextPreview // Import options of csv and txt files
+	TargetName      string       // Name of the converted file
+	TargetExists    bool         // A file of that name exists
+	TargetOpen      bool         // The existing file is open in the editor and cannot be replaced
+	RenamedName     string       // Name used instead of replacing the existing file
+	Conflict        string       // Preselected conflict choice, empty to ask
+	Original        string       // Preselected handling of the original, see config.Original*
+	CanMoveOriginal bool         // The user may archive or delete the original
+	ArchiveDir      string       // Folder originals are archived in
 	Error           string
 }
 
@@ -49,6 +71,10 @@ type templates struct {
 	editor  *template.Template
 	convert *template.Template
 	error   *template.Template
+	newDoc  *template.Template
+	picker  *template.Template
+	home    *template.Template
+	browse  *template.Template
 }
 
 // loadTemplates loads all HTML templates from embedded filesystem
@@ -72,11 +98,41 @@ func (s *Server) loadTemplates() error {
 		return err
 	}
 
+	s.templates.newDoc, err = template.ParseFS(web.Templates, "templates/new.tmpl")
+	if err != nil {
+		return err
+	}
+
+	s.templates.picker, err = template.ParseFS(web.Templates, "templates/picker.tmpl")
+	if err != nil {
+		return err
+	}
+
+	s.templates.home, err = template.ParseFS(web.Templates, "templates/home.tmpl")
+	if err != nil {
+		return err
+	}
+
+	s.templates.browse, err = template.ParseFS(web.Templates, "templates/browse.tmpl")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // handleEditorPage handles GET /editor - renders the editor page
 func (s *Server) handleEditorPage(w http.ResponseWriter, r *http.Request) {
+	s.serveEditor(w, r, false)
+}
+
+// handleEmbedPage handles GET /embed - renders a read-only preview for iframes
+func (s *Server) handleEmbedPage(w http.ResponseWriter, r *http.Request) {
+	s.serveEditor(w, r, true)
+}
+
+// serveEditor renders the editor page, embedded previews are always read-only
+func (s *Server) serveEditor(w http.ResponseWriter, r *http.Request, embedded bool) {
 	// Get file path from query parameter
 	filePath := r.URL.Query().Get("path")
 	if filePath == "" {
@@ -89,6 +145,9 @@ func (s *Server) handleEditorPage(w http.ResponseWriter, r *http.Request) {
 
 	// Get view mode
 	mode := r.URL.Query().Get("mode")
+	if embedded {
+		mode = policy.ModeView
+	}
 
 	// Check settings
 	if s.settings == nil {
@@ -126,21 +185,47 @@ func (s *Server) handleEditorPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if format needs conversion
-	if s.formatManager.IsConvertible(fileInfo.Extension) && mode != "view" {
+	// Open mislabelled files with the type of their content
+	fileInfo, _ = s.sniffFileInfo(fileInfo)
+
+	// Check if format needs conversion, unless it is edited in place always
+	// or on request. Saving a password protected file back needs the
+	// password, so it is converted.
+	inPlace := s.formatManager.IsEditableInPlace(fileInfo.Extension) ||
+		r.URL.Query().Get("inplace") == "1" && s.formatManager.CanRoundTrip(fileInfo.Extension)
+	editInPlace := inPlace && !s.isEncrypted(filePath)
+	if s.formatManager.IsConvertible(fileInfo.Extension) && !editInPlace && mode != policy.ModeView {
 		// Redirect to convert page
 		http.Redirect(w, r, "/convert?path="+url.QueryEscape(filePath), http.StatusFound)
 		return
 	}
 
-	// Get user info from query or use defaults
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		userID = "fnos_user"
-	}
-	userName := r.URL.Query().Get("user_name")
-	if userName == "" {
-		userName = "fnOS 用户"
+	// Get user info from query, cookies or use defaults
+	userID, userName := s.requestUser(w, r)
+
+	// Pick the open mode allowed by the format and the permission policy
+	if formatInfo, ok := s.formatManager.GetFormat(fileInfo.Extension); ok {
+		if editInPlace && !formatInfo.EditInPlace {
+			requested := *formatInfo
+			requested.EditInPlace = true
+			formatInfo = &requested
+		}
+		resolved, _, allowed := s.resolveMode(userID, filePath, mode, formatInfo)
+		if embedded {
+			resolved, _, allowed = s.policy.Resolve(userID, filePath, mode, []string{policy.ModeView})
+		}
+		if !allowed {
+			log.Printf("Policy denies %s access to %s", userID, filePath)
+			s.renderErrorPage(w, &ErrorPageData{
+				Title:   "权限不足",
+				Message: "您没有打开此文档的权限",
+			})
+			return
+		}
+		if mode != "" && resolved != mode {
+			log.Printf("Mode %s not allowed for %s on %s, using %s", mode, userID, filePath, resolved)
+		}
+		mode = resolved
 	}
 
 	// Get language
@@ -156,9 +241,29 @@ func (s *Server) handleEditorPage(w http.ResponseWriter, r *http.Request) {
 		UserID:    userID,
 		UserName:  userName,
 		Lang:      lang,
-		BaseURL:   s.baseURL,
 		JWTSecret: s.settings.DocumentServerSecret,
-		ViewMode:  mode == "view",
+		Mode:      mode,
+		Type:      editorType(r),
+	}
+	if embedded {
+		configReq.Type = editorTypeEmbedded
+	}
+	if editInPlace {
+		// Save back in the format of the content, csv and txt files with the
+		// encoding chosen on the convert page
+		configReq.Callback = parseTextOptions(r.URL.Query(), fileInfo.Extension).values()
+		configReq.Callback.Set("inplace", fileInfo.Extension)
+	} else if mode == policy.ModeView && s.formatManager.IsConvertible(fileInfo.Extension) && !s.isEncrypted(filePath) {
+		// Other convertible formats are viewed as their converted document
+		viewed := *fileInfo
+		viewed.Extension = s.formatManager.GetConvertTarget(fileInfo.Extension)
+		configReq.FileInfo = &viewed
+		configReq.View = viewed.Extension
+	}
+	if action := r.URL.Query().Get("action"); action != "" {
+		if err := json.Unmarshal([]byte(action), &configReq.ActionLink); err != nil {
+			log.Printf("Ignoring invalid action link for %s: %v", filePath, err)
+		}
 	}
 
 	editorConfig, err := s.buildEditorConfig(configReq)
@@ -183,11 +288,18 @@ func (s *Server) handleEditorPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !embedded {
+		s.userdata.AddRecent(userID, filePath)
+	}
+
 	data := &EditorPageData{
 		Title:         fileInfo.Name,
+		FilePath:      filePath,
 		ConfigJSON:    template.JS(configJSON),
 		DocServerPath: s.getDocServerFrontendPath(),
 		Lang:          lang,
+		Notices:       s.pendingNotices(filePath, userID),
+		Embedded:      embedded,
 	}
 
 	// If templates are loaded, use them
@@ -234,6 +346,9 @@ func (s *Server) handleConvertPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Convert mislabelled files from the type of their content
+	fileInfo, warning := s.sniffFileInfo(fileInfo)
+
 	// Get target format
 	targetFormat := s.formatManager.GetConvertTarget(fileInfo.Extension)
 	if targetFormat == "" {
@@ -249,6 +364,39 @@ func (s *Server) handleConvertPage(w http.ResponseWriter, r *http.Request) {
 		SourceFormat:    fileInfo.Extension,
 		TargetFormat:    targetFormat,
 		CanDirectEdit:   false,
+		Warning:         warning,
+		Encrypted:       s.isEncrypted(filePath),
+		Text:            s.textPreview(filePath, fileInfo.Extension),
+		Original:        s.settings.GetConvertOriginal(),
+		ArchiveDir:      s.archiveDir(filePath),
+	}
+	targetPath := s.buildTargetPath(filePath, targetFormat)
+	data.TargetName = filepath.Base(targetPath)
+	if s.fileService.Exists(targetPath) {
+		data.TargetExists = true
+		data.TargetOpen = targetPath == filePath || s.isOpen(targetPath)
+		if renamed, err := s.fileService.UniquePath(convertedName(targetPath)); err == nil {
+			data.RenamedName = filepath.Base(renamed)
+		}
+		if conflict := s.settings.GetConvertConflict(); conflict != config.ConflictAsk {
+			data.Conflict = conflict
+		}
+		if data.TargetOpen {
+			data.Conflict = config.ConflictRename
+		}
+	}
+	if s.formatManager.CanRoundTrip(fileInfo.Extension) && !data.Encrypted {
+		data.InPlaceURL = "/editor?path=" + url.QueryEscape(filePath) + "&inplace=1&mode=" + policy.ModeEdit
+	}
+	userID, _ := s.requestUser(w, r)
+	for _, option := range s.modeOptions(userID, targetPath, targetFormat) {
+		if option.Mode != policy.ModeView {
+			data.Modes = append(data.Modes, option)
+		}
+	}
+	data.CanMoveOriginal = s.canWrite(userID, filePath)
+	if !data.CanMoveOriginal {
+		data.Original = config.OriginalKeep
 	}
 
 	// If templates are loaded, use them
@@ -290,80 +438,113 @@ func (s *Server) renderErrorPage(w http.ResponseWriter, data *ErrorPageData) {
 
 // editorConfigRequest holds parameters for building editor config
 type editorConfigRequest struct {
-	FilePath  string
-	FileInfo  *file.FileInfo
-	UserID    string
-	UserName  string
-	Lang      string
-	BaseURL   string
-	JWTSecret string
-	ViewMode  bool
+	FilePath   string
+	FileInfo   *file.FileInfo
+	UserID     string
+	UserName   string
+	Lang       string
+	JWTSecret  string
+	Mode       string                 // Open mode, see policy.Mode*; empty for the format's preferred mode
+	Type       string                 // Editor type: desktop, mobile or embedded
+	ActionLink map[string]interface{} // Comment to show, from a mention link
+	Callback   url.Values             // Extra parameters of the callback URL
+	View       string                 // Format a convertible file is converted to for viewing
 }
 
-// buildEditorConfig builds the editor configuration
-func (s *Server) buildEditorConfig(req *editorConfigRequest) (map[string]interface{}, error) {
-	// Get format information
+// buildEditorConfig builds the editor configuration with the typed builder,
+// filling in the session key and the menus that depend on server state
+func (s *Server) buildEditorConfig(req *editorConfigRequest) (*editor.EditorConfig, error) {
 	formatInfo, ok := s.formatManager.GetFormat(req.FileInfo.Extension)
 	if !ok {
 		return nil, format.ErrFormatNotSupported
 	}
 
-	// Determine edit mode
-	canEdit := formatInfo.Editable && !req.ViewMode
-	mode := "view"
-	if canEdit {
-		mode = "edit"
-	}
-
-	// Generate document key
-	docKey := s.configBuilder.GetDocumentKey(req.FilePath, req.FileInfo.ModTime)
-
-	// Build download URL
-	downloadURL := s.buildDownloadURL(req.FilePath)
-
-	// Build callback URL
-	callbackURL := s.buildCallbackURL(req.FilePath)
-
-	config := map[string]interface{}{
-		"document": map[string]interface{}{
-			"fileType": req.FileInfo.Extension,
-			"key":      docKey,
-			"title":    req.FileInfo.Name,
-			"url":      downloadURL,
-			"permissions": map[string]interface{}{
-				"edit":     canEdit,
-				"download": true,
-				"print":    true,
-			},
+	// Join the session already open for this file, or start a new one. A
+	// converted copy is a different document than the file.
+	docKey, ok := s.sessions.KeyForPath(req.FilePath, req.FileInfo.ModTime)
+	if !ok || req.View != "" {
+		docKey = s.configBuilder.GetDocumentKey(req.FilePath, req.FileInfo.ModTime)
+	}
+	var download url.Values
+	if req.View != "" {
+		docKey += "_" + req.View
+		download = url.Values{"view": {req.View}}
+	}
+
+	// "Create New" menu targets the folder of the current document
+	folder := filepath.Dir(req.FilePath)
+	kind := newdoc.KindForDocumentType(formatInfo.Type)
+	favorite := s.userdata.IsFavorite(req.UserID, req.FilePath)
+
+	configReq := &editor.ConfigRequest{
+		FilePath:   req.FilePath,
+		FileInfo:   req.FileInfo,
+		Key:        docKey,
+		UserID:     req.UserID,
+		UserName:   req.UserName,
+		Lang:       req.Lang,
+		BaseURL:    s.getEffectiveBaseURL(),
+		JWTSecret:  req.JWTSecret,
+		Mode:       req.Mode,
+		Type:       req.Type,
+		Profile:    s.customization,
+		MacrosMode: s.policy.MacrosMode(req.UserID, req.FilePath),
+		Goback:     &editor.GobackConfig{URL: s.folderURL(folder), Text: "打开文件位置"},
+		Info: &editor.DocumentInfo{
+			Folder:   folder,
+			Uploaded: req.FileInfo.ModTime.Format("2006-01-02 15:04"),
+			Favorite: &favorite,
 		},
-		"documentType": formatInfo.Type,
-		"editorConfig": map[string]interface{}{
-			"callbackUrl": callbackURL,
-			"lang":        req.Lang,
-			"mode":        mode,
-			"user": map[string]interface{}{
-				"id":   req.UserID,
-				"name": req.UserName,
-			},
+		ReferenceData: &editor.ReferenceDataConfig{
+			FileKey:    s.fileids.ID(req.FilePath),
+			InstanceID: s.fileids.InstanceID(),
 		},
+		CreateURL:     newDocumentURL(folder, kind, ""),
+		Templates:     s.editorTemplates(folder, kind),
+		Recent:        s.editorRecent(req.UserID, req.FilePath),
+		ActionLink:    req.ActionLink,
+		CallbackQuery: req.Callback,
+		DownloadQuery: download,
 	}
-
-	// Sign the configuration with JWT if secret is provided
 	if req.JWTSecret != "" {
-		token, err := s.jwtManager.Sign(req.JWTSecret, config)
-		if err != nil {
-			return nil, err
+		configReq.Signer = func(claims map[string]interface{}) (string, error) {
+			return s.signTokenWithExpiry(claims, s.settings.GetJWTExpiry())
 		}
-		config["token"] = token
 	}
 
+	config, err := s.configBuilder.BuildConfig(configReq)
+	if err != nil {
+		return nil, err
+	}
+	if config.EditorConfig.Mode == "edit" {
+		s.sessions.Register(docKey, req.FilePath, req.FileInfo.ModTime)
+	}
 	return config, nil
 }
 
-// buildCallbackURL builds the callback URL for a file
-func (s *Server) buildCallbackURL(filePath string) string {
-	baseURL := s.getEffectiveBaseURL()
-	return baseURL + "/callback?path=" + url.QueryEscape(filePath)
+// editorRecent builds the editorConfig.recent list for the File menu
+func (s *Server) editorRecent(userID, currentPath string) []editor.RecentConfig {
+	var result []editor.RecentConfig
+	for _, entry := range s.userdata.Recent(userID) {
+		if entry.Path == currentPath {
+			continue
+		}
+		result = append(result, editor.RecentConfig{
+			Folder: filepath.Dir(entry.Path),
+			Title:  entry.Name,
+			URL:    "/editor?path=" + url.QueryEscape(entry.Path),
+		})
+	}
+	return result
+}
+
+// folderURL returns the browser URL of a folder, the fnOS file manager if
+// configured, otherwise the connector file browser
+func (s *Server) folderURL(folder string) string {
+	if filesURL := s.settings.GetFilesURL(folder); filesURL != "" {
+		return filesURL
+	}
+	return "/?folder=" + url.QueryEscape(folder)
 }
 
 // Fallback renderers for when templates are not available
@@ -411,6 +592,7 @@ func (s *Server) renderConvertPageFallback(w http.ResponseWriter, data *ConvertP
     <div id="error"></div>
     <form hx-post="/convert" hx-target="#error" hx-swap="innerHTML">
         <input type="hidden" name="path" value="` + data.FilePath + `">
+        <input type="hidden" name="conflict" value="rename">
         <button type="submit" class="btn btn-primary">转换为 ` + data.TargetFormat + ` 并编辑</button>
     </form>
     <a href="/editor?path=` + data.FilePathEncoded + `&mode=view" class="btn btn-secondary">以只读模式查看</a>
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/editor"
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/jwt"
+)
+
+// Test the editor config lists the user's recent files and links back to the folder
+func TestEditorConfigRecentAndGoback(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1", "docs"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "docs", "current.docx"), []byte("doc"), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.userdata.AddRecent("alice", "/vol1/other.xlsx")
+	server.userdata.AddRecent("alice", "/vol1/docs/current.docx")
+
+	fileInfo, _ := server.fileService.GetFileInfo("/vol1/docs/current.docx")
+	build := func() editor.EditorConfigInner {
+		config, err := server.buildEditorConfig(&editorConfigRequest{
+			FilePath: "/vol1/docs/current.docx",
+			FileInfo: fileInfo,
+			UserID:   "alice",
+			Lang:     "zh",
+		})
+		if err != nil {
+			t.Fatalf("Failed to build config: %v", err)
+		}
+		return config.EditorConfig
+	}
+
+	editorConfig := build()
+	recent := editorConfig.Recent
+	if len(recent) != 1 || recent[0].Title != "other.xlsx" || recent[0].Folder != "/vol1" || recent[0].URL != "/editor?path=%2Fvol1%2Fother.xlsx" {
+		t.Errorf("Unexpected recent list %v", recent)
+	}
+
+	goback := editorConfig.Customization["goback"].(map[string]interface{})
+	if goback["url"] != "/?folder=%2Fvol1%2Fdocs" {
+		t.Errorf("Expected connector browser URL, got %v", goback["url"])
+	}
+
+	server.settings.FilesURL = "https://nas.local/files?path={folder}"
+	goback = build().Customization["goback"].(map[string]interface{})
+	if goback["url"] != "https://nas.local/files?path=%2Fvol1%2Fdocs" {
+		t.Errorf("Expected configured files URL, got %v", goback["url"])
+	}
+}
+
+// Test the customization profile is merged into the signed editor config
+func TestEditorConfigCustomization(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "a.docx"), []byte("doc"), 0644)
+
+	jwtManager := jwt.NewManager()
+	secret := jwtManager.GenerateSecret()
+	server := New(&Config{
+		Settings: &config.Settings{
+			DocumentServerURL:    "http://example.com",
+			DocumentServerSecret: secret,
+		},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwtManager,
+		Customization: &editor.Profile{
+			Default: map[string]interface{}{"compactHeader": true},
+			Users:   map[string]map[string]interface{}{"alice": {"uiTheme": "theme-dark"}},
+		},
+		BaseURL: "http://localhost:10099",
+	})
+
+	fileInfo, _ := server.fileService.GetFileInfo("/vol1/a.docx")
+	cfg, err := server.buildEditorConfig(&editorConfigRequest{
+		FilePath:  "/vol1/a.docx",
+		FileInfo:  fileInfo,
+		UserID:    "alice",
+		JWTSecret: secret,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build config: %v", err)
+	}
+
+	claims, err := jwtManager.Verify(secret, cfg.Token)
+	if err != nil {
+		t.Fatalf("Invalid config token: %v", err)
+	}
+	customization := claims["editorConfig"].(map[string]interface{})["customization"].(map[string]interface{})
+	if customization["forcesave"] != true || customization["compactHeader"] != true || customization["uiTheme"] != "theme-dark" {
+		t.Errorf("Unexpected signed customization %v", customization)
+	}
+	if _, ok := customization["goback"]; !ok {
+		t.Errorf("Expected goback in customization")
+	}
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"onlyoffice-fnos/internal/file"
+)
+
+// Picker types requested by the editor events
+const (
+	PickImage       = "image"       // onRequestInsertImage
+	PickDocument    = "document"    // onRequestSelectDocument (compare, combine, insert text)
+	PickSpreadsheet = "spreadsheet" // onRequestSelectSpreadsheet (mail merge)
+	PickReference   = "reference"   // onRequestReferenceSource (change the source of external links)
+)
+
+// pickedURLExpiry is the lifetime of download URLs handed to the editor
+const pickedURLExpiry = 5 * time.Minute
+
+// imageExtensions lists the image types the Document Server can insert
+var imageExtensions = map[string]bool{
+	"bmp": true, "gif": true, "jpeg": true, "jpg": true, "png": true, "svg": true, "tif": true, "tiff": true, "webp": true,
+}
+
+// PickerPageData holds data for the file picker fragment
+type PickerPageData struct {
+	Type    string
+	Key     string // Editing session the picker was opened from
+	Folder  string
+	Parent  string
+	HasUp   bool
+	Entries []*file.FileInfo
+	Error   string
+}
+
+// PickRequest represents a file chosen in the picker
+type PickRequest struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+	C    string `json:"c"`   // Command from the editor event, e.g. "add", "compare", "mailmerge"
+	Key  string `json:"key"` // Editing session the picker was opened from
+}
+
+// handlePicker handles GET /picker - renders a folder listing for the picker dialog
+func (s *Server) handlePicker(w http.ResponseWriter, r *http.Request) {
+	pickType := r.URL.Query().Get("type")
+	if !validPickType(pickType) {
+		s.respondError(w, http.StatusBadRequest, "Invalid picker type")
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	sess, ok := s.sessions.Lookup(key)
+	if !ok {
+		s.respondError(w, http.StatusForbidden, "Editing session required")
+		return
+	}
+
+	data := &PickerPageData{
+		Type:   pickType,
+		Key:    key,
+		Folder: r.URL.Query().Get("folder"),
+	}
+	if _, ok := r.URL.Query()["folder"]; !ok {
+		data.Folder = filepath.Dir(sess.Path)
+	}
+	root := s.pickerRoot(sess.Path)
+	if !withinFolder(data.Folder, root) {
+		log.Printf("Picker error: %s is outside of %s", data.Folder, root)
+		data.Folder = root
+	}
+
+	userID, _ := s.requestUser(w, r)
+	folder, entries, err := s.listFolder(data.Folder)
+	if err != nil {
+		log.Printf("Picker error: failed to list %s: %v", data.Folder, err)
+		data.Error = "文件夹不存在或无法访问"
+	}
+	data.Folder = folder
+	for _, entry := range entries {
+		if entry.IsDir || s.pickable(pickType, entry.Extension) && s.canRead(userID, entry.Path) {
+			data.Entries = append(data.Entries, entry)
+		}
+	}
+	if folder != "" && filepath.Clean(folder) != root {
+		data.Parent, data.HasUp = s.folderParent(folder)
+	}
+
+	if s.templates == nil || s.templates.picker == nil {
+		s.respondError(w, http.StatusInternalServerError, "Picker not available")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.picker.Execute(w, data); err != nil {
+		log.Printf("Failed to render picker template: %v", err)
+	}
+}
+
+// handlePick handles POST /picker/select
+// It returns the object expected by insertImage, setRequestedDocument,
+// setRequestedSpreadsheet or setReferenceSource, with a short-lived download
+// URL and a signed token.
+func (s *Server) handlePick(w http.ResponseWriter, r *http.Request) {
+	var req PickRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if !validPickType(req.Type) || req.Path == "" {
+		s.respondError(w, http.StatusBadRequest, "Type and path are required")
+		return
+	}
+	sess, ok := s.sessions.Lookup(req.Key)
+	if !ok {
+		s.respondError(w, http.StatusForbidden, "Editing session required")
+		return
+	}
+	if !withinFolder(req.Path, s.pickerRoot(sess.Path)) {
+		log.Printf("Picker error: %s is outside of the folder of %s", req.Path, sess.Path)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	fileInfo, err := s.fileService.GetFileInfo(req.Path)
+	if err != nil {
+		log.Printf("Picker error: %s: %v", req.Path, err)
+		switch err {
+		case file.ErrFileNotFound:
+			s.respondError(w, http.StatusNotFound, "File not found")
+		case file.ErrPermissionDenied:
+			s.respondError(w, http.StatusForbidden, "Permission denied")
+		default:
+			s.respondError(w, http.StatusBadRequest, "Invalid file path")
+		}
+		return
+	}
+	if !s.pickable(req.Type, fileInfo.Extension) {
+		s.respondError(w, http.StatusBadRequest, "File type not supported")
+		return
+	}
+	userID, _ := s.requestUser(w, r)
+	if !s.canRead(userID, req.Path) {
+		log.Printf("Policy denies %s access to %s", userID, req.Path)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	if req.Type == PickReference {
+		payload, status, err := s.referencePayload(userID, sess.Path, req.Path)
+		if err != nil {
+			log.Printf("Picker error: reference %s: %v", req.Path, err)
+			s.respondError(w, status, err.Error())
+			return
+		}
+		log.Printf("Picked %s as reference source for %s", req.Path, sess.Path)
+		s.respondJSON(w, http.StatusOK, payload)
+		return
+	}
+
+	downloadURL, err := s.signedDownloadURL(req.Path, pickedURLExpiry)
+	if err != nil {
+		log.Printf("Picker error: failed to sign URL for %s: %v", req.Path, err)
+		s.respondError(w, http.StatusInternalServerError, "Failed to sign URL")
+		return
+	}
+
+	var payload map[string]interface{}
+	if req.Type == PickImage {
+		payload = map[string]interface{}{
+			"c": req.C,
+			"images": []interface{}{
+				map[string]interface{}{"fileType": fileInfo.Extension, "url": downloadURL},
+			},
+		}
+	} else {
+		payload = map[string]interface{}{
+			"c":        req.C,
+			"fileType": fileInfo.Extension,
+			"url":      downloadURL,
+		}
+	}
+
+	if s.jwtEnabled() {
+		token, err := s.signTokenWithExpiry(payload, pickedURLExpiry)
+		if err != nil {
+			log.Printf("Picker error: failed to sign payload: %v", err)
+			s.respondError(w, http.StatusInternalServerError, "Failed to sign payload")
+			return
+		}
+		payload["token"] = token
+	}
+
+	log.Printf("Picked %s for %s (%s)", req.Path, req.Type, req.C)
+	s.respondJSON(w, http.StatusOK, payload)
+}
+
+// signedDownloadURL builds a download URL that carries its own token, so the
+// Document Server can fetch files that are not the document being edited
+func (s *Server) signedDownloadURL(filePath string, expiry time.Duration) (string, error) {
+	downloadURL := s.buildDownloadURL(filePath)
+	if !s.jwtEnabled() {
+		return downloadURL, nil
+	}
+	token, err := s.signTokenWithExpiry(map[string]interface{}{"path": filePath}, expiry)
+	if err != nil {
+		return "", err
+	}
+	return downloadURL + "&token=" + url.QueryEscape(token), nil
+}
+
+// pickable reports whether a file extension can be chosen for the picker type
+func (s *Server) pickable(pickType, ext string) bool {
+	switch pickType {
+	case PickImage:
+		return imageExtensions[ext]
+	case PickDocument:
+		return s.formatManager.GetDocumentType(ext) == "word"
+	case PickSpreadsheet, PickReference:
+		return s.formatManager.GetDocumentType(ext) == "cell"
+	}
+	return false
+}
+
+// pickerRoot returns the folder the picker of a document cannot leave: the
+// folder of the document, or "" if shared folders already restrict file access
+func (s *Server) pickerRoot(documentPath string) string {
+	if len(s.fileService.GetAllowedRoots()) > 0 {
+		return ""
+	}
+	return filepath.Dir(filepath.Clean("/" + documentPath))
+}
+
+// withinFolder reports whether path is folder or lies below it. Every path is
+// within the empty folder.
+func withinFolder(path, folder string) bool {
+	if folder == "" {
+		return true
+	}
+	clean := filepath.Clean("/" + path)
+	return clean == folder || folder == "/" || strings.HasPrefix(clean, folder+"/")
+}
+
+// listFolder lists a folder for the browser and picker pages. Without a folder
+// the shared folders are listed, or "/" if file access is not restricted.
+func (s *Server) listFolder(folder string) (string, []*file.FileInfo, error) {
+	roots := s.fileService.GetAllowedRoots()
+	if folder == "" && len(roots) > 0 {
+		entries := make([]*file.FileInfo, 0, len(roots))
+		for _, root := range roots {
+			entries = append(entries, &file.FileInfo{Path: root, Name: root, IsDir: true})
+		}
+		return "", entries, nil
+	}
+
+	if folder == "" {
+		folder = "/"
+	}
+	entries, err := s.fileService.ListDir(folder)
+	return folder, entries, err
+}
+
+// folderParent returns the folder above folder, or the root list ("") when folder is a shared folder
+func (s *Server) folderParent(folder string) (string, bool) {
+	clean := filepath.Clean("/" + folder)
+	for _, root := range s.fileService.GetAllowedRoots() {
+		if clean == root {
+			return "", true
+		}
+	}
+	if clean == "/" {
+		return "", false
+	}
+	parent := filepath.Dir(clean)
+	if s.fileService.CheckAccess(parent) != nil {
+		return "", len(s.fileService.GetAllowedRoots()) > 0
+	}
+	return parent, true
+}
+
+// validPickType reports whether t is a known picker type
+func validPickType(t string) bool {
+	return t == PickImage || t == PickDocument || t == PickSpreadsheet || t == PickReference
+}
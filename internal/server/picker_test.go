@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/jwt"
+)
+
+// Test the picker lists folders and matching files only
+func TestPickerListing(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1", "photos", "2024"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "photos", "cat.png"), []byte("png"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "photos", "notes.docx"), []byte("doc"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "photos", ".hidden.png"), []byte("png"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "secret.png"), []byte("png"), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.sessions.Register("photos-key", "/vol1/photos/notes.docx", time.Now())
+
+	get := func(target string) (int, string) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	status, body := get("/picker?type=image&key=photos-key")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if !strings.Contains(body, "cat.png") || !strings.Contains(body, "2024") {
+		t.Errorf("Expected image and folder in listing: %s", body)
+	}
+	if strings.Contains(body, "notes.docx") || strings.Contains(body, ".hidden.png") {
+		t.Errorf("Unexpected entries in image listing: %s", body)
+	}
+	if strings.Contains(body, "上一级") {
+		t.Errorf("Expected the folder of the document to be the top of the picker")
+	}
+
+	// Without shared folders the picker stays in the folder of the document
+	if _, body = get("/picker?type=image&key=photos-key&folder=/vol1"); strings.Contains(body, "secret.png") || !strings.Contains(body, "cat.png") {
+		t.Errorf("Expected the listing to stay below the document folder: %s", body)
+	}
+	if status, _ = get("/picker?type=image&folder=" + url.QueryEscape("/vol1/photos")); status != http.StatusForbidden {
+		t.Errorf("Expected 403 without an editing session, got %d", status)
+	}
+
+	if _, body = get("/picker?type=document&key=photos-key&folder=" + url.QueryEscape("/vol1/photos")); !strings.Contains(body, "notes.docx") || strings.Contains(body, "cat.png") {
+		t.Errorf("Unexpected document listing: %s", body)
+	}
+
+	if status, _ = get("/picker?type=video&key=photos-key"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown type, got %d", status)
+	}
+}
+
+// Test picked files get a signed payload and a short-lived download URL
+func TestPickSignedURL(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "cat.png"), []byte("png"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "other.png"), []byte("png"), 0644)
+
+	jwtManager := jwt.NewManager()
+	secret := jwtManager.GenerateSecret()
+
+	server := New(&Config{
+		Settings: &config.Settings{
+			DocumentServerURL:    "http://example.com",
+			DocumentServerSecret: secret,
+		},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwtManager,
+		BaseURL:       "http://localhost:10099",
+	})
+	server.sessions.Register("doc-key", "/vol1/doc.docx", time.Now())
+
+	// Files are only handed out to an editing session
+	body, _ := json.Marshal(PickRequest{Type: PickImage, Path: "/vol1/cat.png", C: "add"})
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("POST", "/picker/select", bytes.NewReader(body)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 without an editing session, got %d", rec.Code)
+	}
+
+	body, _ = json.Marshal(PickRequest{Type: PickImage, Path: "/vol1/cat.png", C: "add", Key: "doc-key"})
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("POST", "/picker/select", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		C      string `json:"c"`
+		Images []struct {
+			FileType string `json:"fileType"`
+			URL      string `json:"url"`
+		} `json:"images"`
+		Token string `json:"token"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.C != "add" || len(resp.Images) != 1 || resp.Images[0].FileType != "png" {
+		t.Fatalf("Unexpected payload %+v", resp)
+	}
+	if _, err := jwtManager.Verify(secret, resp.Token); err != nil {
+		t.Fatalf("Payload token invalid: %v", err)
+	}
+
+	// The signed URL downloads without a Document Server header token
+	signed, _ := url.Parse(resp.Images[0].URL)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", signed.RequestURI(), nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "png" {
+		t.Fatalf("Signed download failed: %d", rec.Code)
+	}
+
+	// The token is bound to the picked file
+	other := signed.Query()
+	other.Set("path", "/vol1/other.png")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/download?"+other.Encode(), nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for another file, got %d", rec.Code)
+	}
+
+	// Files of the wrong type are rejected
+	body, _ = json.Marshal(PickRequest{Type: PickSpreadsheet, Path: "/vol1/cat.png", C: "mailmerge", Key: "doc-key"})
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("POST", "/picker/select", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for wrong type, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"onlyoffice-fnos/internal/editor"
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/policy"
+)
+
+var errNotSpreadsheet = errors.New("linked file is not a spreadsheet")
+
+// ReferenceRequest is sent by the editor page for onRequestReferenceData
+type ReferenceRequest struct {
+	Key           string                      `json:"key"`           // Editing session of the document containing the link
+	ReferenceData *editor.ReferenceDataConfig `json:"referenceData"` // Identifier stored with the link
+	ReferencePath string                      `json:"referencePath"` // File name stored with the link
+	Link          string                      `json:"link"`          // URL stored with the link
+}
+
+// handleReferenceData handles POST /reference - resolves an external link of
+// a spreadsheet to the linked workbook, for setReferenceData
+func (s *Server) handleReferenceData(w http.ResponseWriter, r *http.Request) {
+	var req ReferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	sess, ok := s.sessions.Lookup(req.Key)
+	if !ok {
+		s.respondError(w, http.StatusForbidden, "Editing session required")
+		return
+	}
+
+	target, ok := s.resolveReference(sess.Path, &req)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "Linked file not found")
+		return
+	}
+	// Links may only reach the files the picker offers
+	if !withinFolder(target, s.pickerRoot(sess.Path)) {
+		log.Printf("Reference error: %s is outside of the folder of %s", target, sess.Path)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	userID, _ := s.requestUser(w, r)
+	payload, status, err := s.referencePayload(userID, sess.Path, target)
+	if err != nil {
+		log.Printf("Reference error: %s -> %s: %v", sess.Path, target, err)
+		s.respondError(w, status, err.Error())
+		return
+	}
+
+	log.Printf("Resolved reference from %s to %s", sess.Path, target)
+	s.respondJSON(w, http.StatusOK, payload)
+}
+
+// resolveReference finds the linked file by its stable identifier, then by
+// the stored path relative to the document, then by a connector editor link
+func (s *Server) resolveReference(documentPath string, req *ReferenceRequest) (string, bool) {
+	if ref := req.ReferenceData; ref != nil && ref.InstanceID == s.fileids.InstanceID() {
+		if target, ok := s.fileids.Path(ref.FileKey); ok && s.fileService.Exists(target) {
+			return target, true
+		}
+	}
+
+	if req.ReferencePath != "" {
+		target := req.ReferencePath
+		if !strings.HasPrefix(target, "/") {
+			target = filepath.Join(filepath.Dir(documentPath), target)
+		}
+		if s.fileService.Exists(target) {
+			return filepath.Clean(target), true
+		}
+	}
+
+	if link, err := url.Parse(req.Link); err == nil && link.Path == "/editor" {
+		if target := link.Query().Get("path"); target != "" && s.fileService.Exists(target) {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// referencePayload builds the object expected by setReferenceData and
+// setReferenceSource. The user must be allowed to open the linked workbook.
+func (s *Server) referencePayload(userID, sourcePath, targetPath string) (map[string]interface{}, int, error) {
+	fileInfo, err := s.fileService.GetFileInfo(targetPath)
+	if err != nil {
+		switch err {
+		case file.ErrFileNotFound:
+			return nil, http.StatusNotFound, err
+		case file.ErrPermissionDenied:
+			return nil, http.StatusForbidden, err
+		default:
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
+	formatInfo, ok := s.formatManager.GetFormat(fileInfo.Extension)
+	if !ok || formatInfo.Type != "cell" {
+		return nil, http.StatusBadRequest, errNotSpreadsheet
+	}
+	if _, _, allowed := s.resolveMode(userID, targetPath, policy.ModeView, formatInfo); !allowed {
+		return nil, http.StatusForbidden, file.ErrPermissionDenied
+	}
+
+	downloadURL, err := s.signedDownloadURL(targetPath, pickedURLExpiry)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	key, ok := s.sessions.KeyForPath(targetPath, fileInfo.ModTime)
+	if !ok {
+		key = s.configBuilder.GetDocumentKey(targetPath, fileInfo.ModTime)
+	}
+
+	payload := map[string]interface{}{
+		"fileType": fileInfo.Extension,
+		"key":      key,
+		"path":     referencePath(sourcePath, targetPath),
+		"referenceData": map[string]interface{}{
+			"fileKey":    s.fileids.ID(targetPath),
+			"instanceId": s.fileids.InstanceID(),
+		},
+		"url": downloadURL,
+	}
+	if s.jwtEnabled() {
+		token, err := s.signTokenWithExpiry(payload, pickedURLExpiry)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		payload["token"] = token
+	}
+	return payload, http.StatusOK, nil
+}
+
+// referencePath returns the path stored with a link, relative to the
+// document folder when the workbook is below it
+func referencePath(sourcePath, targetPath string) string {
+	rel, err := filepath.Rel(filepath.Dir(sourcePath), targetPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return targetPath
+	}
+	return rel
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"onlyoffice-fnos/internal/editor"
+	"onlyoffice-fnos/internal/policy"
+)
+
+// Test external links resolve by stable file ID, by relative path and respect the policy
+func TestReferenceData(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1", "finance", "q1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "finance", "report.xlsx"), []byte("report"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "finance", "q1", "sales.xlsx"), []byte("sales"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "finance", "budget-2024.xlsx"), []byte("budget"), 0644)
+	os.MkdirAll(filepath.Join(tempDir, "vol2"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol2", "salaries.xlsx"), []byte("salaries"), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.sessions.Register("report-key", "/vol1/finance/report.xlsx", time.Now())
+	server.policy = &policy.Policy{Rules: []policy.Rule{
+		{Users: []string{"bob"}, Paths: []string{"/vol1/finance/q1"}, Modes: []string{}},
+	}}
+
+	// The budget was linked before it was renamed
+	budgetID := server.fileids.ID("/vol1/finance/budget.xlsx")
+	server.fileids.Rename("/vol1/finance/budget.xlsx", "/vol1/finance/budget-2024.xlsx")
+
+	resolve := func(user string, req *ReferenceRequest) (int, map[string]interface{}) {
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/reference?user_id="+user, bytes.NewReader(body)))
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	code, resp := resolve("alice", &ReferenceRequest{
+		Key:           "report-key",
+		ReferenceData: &editor.ReferenceDataConfig{FileKey: budgetID, InstanceID: server.fileids.InstanceID()},
+		ReferencePath: "budget.xlsx",
+	})
+	if code != 200 || resp["path"] != "budget-2024.xlsx" || resp["fileType"] != "xlsx" {
+		t.Fatalf("Expected renamed budget, got %d %v", code, resp)
+	}
+	if ref := resp["referenceData"].(map[string]interface{}); ref["fileKey"] != budgetID {
+		t.Errorf("Expected stable file key %s, got %v", budgetID, ref)
+	}
+	if !strings.Contains(resp["url"].(string), "/download?path=%2Fvol1%2Ffinance%2Fbudget-2024.xlsx") {
+		t.Errorf("Unexpected download URL %v", resp["url"])
+	}
+
+	// Links from other connectors fall back to the stored path
+	code, resp = resolve("alice", &ReferenceRequest{
+		Key:           "report-key",
+		ReferenceData: &editor.ReferenceDataConfig{FileKey: budgetID, InstanceID: "http://other:10099"},
+		ReferencePath: "q1/sales.xlsx",
+	})
+	if code != 200 || resp["path"] != "q1/sales.xlsx" {
+		t.Errorf("Expected sales by relative path, got %d %v", code, resp)
+	}
+
+	if code, _ = resolve("bob", &ReferenceRequest{Key: "report-key", ReferencePath: "q1/sales.xlsx"}); code != 403 {
+		t.Errorf("Expected policy denial for bob, got %d", code)
+	}
+	if code, _ = resolve("alice", &ReferenceRequest{Key: "report-key", ReferencePath: "missing.xlsx"}); code != 404 {
+		t.Errorf("Expected 404 for missing link, got %d", code)
+	}
+
+	// Links resolve only for an editing session and within the document's folder
+	for _, key := range []string{"", "unknown"} {
+		if code, _ = resolve("alice", &ReferenceRequest{Key: key, ReferencePath: "q1/sales.xlsx"}); code != 403 {
+			t.Errorf("Expected 403 for session key %q, got %d", key, code)
+		}
+	}
+	for _, path := range []string{"/vol2/salaries.xlsx", "../../vol2/salaries.xlsx"} {
+		if code, resp = resolve("alice", &ReferenceRequest{Key: "report-key", ReferencePath: path}); code != 403 || resp["url"] != nil {
+			t.Errorf("Expected 403 for %s outside the folder, got %d %v", path, code, resp)
+		}
+	}
+
+	// The editor config carries the document's own stable ID
+	fileInfo, _ := server.fileService.GetFileInfo("/vol1/finance/budget-2024.xlsx")
+	cfg, err := server.buildEditorConfig(&editorConfigRequest{FilePath: "/vol1/finance/budget-2024.xlsx", FileInfo: fileInfo, UserID: "alice"})
+	if err != nil || cfg.Document.ReferenceData == nil || cfg.Document.ReferenceData.FileKey != budgetID || cfg.Document.ReferenceData.InstanceID != server.fileids.InstanceID() {
+		t.Errorf("Expected referenceData with %s, got %+v %v", budgetID, cfg.Document.ReferenceData, err)
+	}
+}
+
+// Test a new link source is chosen in the picker
+func TestPickReferenceSource(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "report.xlsx"), []byte("report"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "notes.docx"), []byte("notes"), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.sessions.Register("main-key", "/vol1/main.xlsx", time.Now())
+
+	pick := func(path string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(&PickRequest{Type: PickReference, Path: path, Key: "main-key"})
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/picker/select", bytes.NewReader(body)))
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	if code, resp := pick("/vol1/report.xlsx"); code != 200 || resp["path"] != "report.xlsx" || resp["referenceData"] == nil {
+		t.Errorf("Unexpected reference source %d %v", code, resp)
+	}
+	if code, _ := pick("/vol1/notes.docx"); code != 400 {
+		t.Errorf("Expected documents to be rejected, got %d", code)
+	}
+}
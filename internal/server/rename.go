@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"onlyoffice-fnos/internal/file"
+)
+
+// RenameRequest represents a rename request posted by the editor page
+type RenameRequest struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+}
+
+// handleRename handles POST /rename
+// The editor posts the new title from onRequestRename. The file is renamed,
+// the session follows the new path and all co-editors receive the new title.
+func (s *Server) handleRename(w http.ResponseWriter, r *http.Request) {
+	var req RenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	// Only an editing session may rename its file. The session knows the
+	// current path even if another co-editor renamed the file.
+	sess, ok := s.sessions.Lookup(req.Key)
+	if !ok {
+		s.respondError(w, http.StatusForbidden, "Editing session required")
+		return
+	}
+	filePath := sess.Path
+	if userID, _ := s.requestUser(w, r); !s.canWrite(userID, filePath) {
+		log.Printf("Policy denies %s renaming %s", userID, filePath)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	newName, ok := renameTarget(filePath, req.Title)
+	if !ok {
+		s.respondError(w, http.StatusBadRequest, "Invalid file name")
+		return
+	}
+
+	newPath, err := s.fileService.Rename(filePath, newName)
+	if err != nil {
+		log.Printf("Rename error: %s -> %s: %v", filePath, newName, err)
+		switch err {
+		case file.ErrFileExists:
+			s.respondError(w, http.StatusConflict, "A file with this name already exists")
+		case file.ErrFileNotFound:
+			s.respondError(w, http.StatusNotFound, "File not found")
+		case file.ErrPermissionDenied:
+			s.respondError(w, http.StatusForbidden, "Permission denied")
+		case file.ErrInvalidPath:
+			s.respondError(w, http.StatusBadRequest, "Invalid file name")
+		default:
+			s.respondError(w, http.StatusInternalServerError, "Failed to rename file")
+		}
+		return
+	}
+
+	s.sessions.Rename(filePath, newPath)
+	s.userdata.Rename(filePath, newPath)
+	s.fileids.Rename(filePath, newPath)
+	log.Printf("Renamed %s to %s", filePath, newPath)
+
+	// Push the new title to every editor of the session
+	_, err = s.callCommandService(map[string]interface{}{
+		"c":    "meta",
+		"key":  sess.Key,
+		"meta": map[string]interface{}{"title": newName},
+	})
+	if err != nil {
+		log.Printf("Rename: failed to update title via command service: %v", err)
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"path":    newPath,
+		"title":   newName,
+	})
+}
+
+// renameTarget builds the new file name from the editor title, keeping the
+// original extension so the file stays associated with the same format
+func renameTarget(filePath, title string) (string, bool) {
+	title = strings.TrimSpace(title)
+	if title == "" || strings.ContainsAny(title, `/\`) || strings.HasPrefix(title, ".") {
+		return "", false
+	}
+
+	ext := filepath.Ext(filePath)
+	if !strings.EqualFold(filepath.Ext(title), ext) {
+		title += ext
+	}
+	return title, true
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/jwt"
+)
+
+// Test rename moves the file, updates the session and pushes the new title
+func TestRename(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1", "docs"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "docs", "draft.docx"), []byte("doc"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "docs", "taken.docx"), []byte("other"), 0644)
+
+	var commands []map[string]interface{}
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmd map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&cmd)
+		commands = append(commands, cmd)
+		w.Write([]byte(`{"error":0}`))
+	}))
+	defer mockDocServer.Close()
+
+	server := New(&Config{
+		Settings: &config.Settings{
+			DocumentServerURL: mockDocServer.URL,
+		},
+		FileService:   file.NewService(tempDir, 0),
+		FormatManager: format.NewManager(),
+		JWTManager:    jwt.NewManager(),
+		BaseURL:       "http://localhost:10099",
+	})
+	server.sessions.Register("key-1", "/vol1/docs/draft.docx", time.Time{})
+
+	rename := func(req RenameRequest) (*httptest.ResponseRecorder, map[string]interface{}) {
+		body, _ := json.Marshal(req)
+		httpReq := httptest.NewRequest("POST", "/rename", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httpReq)
+
+		var resp map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec, resp
+	}
+
+	rec, resp := rename(RenameRequest{Key: "key-1", Title: "final"})
+	if rec.Code != http.StatusOK || resp["path"] != "/vol1/docs/final.docx" {
+		t.Fatalf("Unexpected response %d %v", rec.Code, resp)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "vol1", "docs", "final.docx")); err != nil {
+		t.Fatalf("Renamed file missing: %v", err)
+	}
+	if sess, ok := server.sessions.Lookup("key-1"); !ok || sess.Path != "/vol1/docs/final.docx" {
+		t.Fatalf("Session not moved: %v", sess)
+	}
+	if len(commands) != 1 || commands[0]["c"] != "meta" || commands[0]["key"] != "key-1" {
+		t.Fatalf("Unexpected command service calls %v", commands)
+	}
+	if meta, _ := commands[0]["meta"].(map[string]interface{}); meta["title"] != "final.docx" {
+		t.Fatalf("Unexpected meta %v", commands[0]["meta"])
+	}
+
+	// A co-editor's rename resolves the current path through the session
+	rec, _ = rename(RenameRequest{Key: "key-1", Title: "taken.docx"})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 for existing name, got %d", rec.Code)
+	}
+
+	// Path separators are rejected
+	rec, _ = rename(RenameRequest{Key: "key-1", Title: "../escape"})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for invalid name, got %d", rec.Code)
+	}
+
+	// Renaming needs a live editing session
+	for _, key := range []string{"", "unknown"} {
+		rec, _ = rename(RenameRequest{Key: key, Title: "other"})
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for session key %q, got %d", key, rec.Code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "vol1", "docs", "final.docx")); err != nil || len(commands) != 1 {
+		t.Errorf("Expected the file and the editors untouched, got %v after %d commands", err, len(commands))
+	}
+}
+
+// Test callbacks follow a renamed session
+func TestCallbackFollowsRenamedSession(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("saved"))
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.sessions.Register("key-2", "/vol1/renamed.docx", time.Time{})
+
+	body, _ := json.Marshal(CallbackRequest{Key: "key-2", Status: StatusSaved, URL: mockDocServer.URL + "/out.docx"})
+	httpReq := httptest.NewRequest("POST", "/callback?path=%2Fvol1%2Foriginal.docx", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httpReq)
+
+	if data, err := os.ReadFile(filepath.Join(tempDir, "vol1", "renamed.docx")); err != nil || string(data) != "saved" {
+		t.Fatalf("Document not saved to renamed path: %v %q", err, data)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "vol1", "original.docx")); !os.IsNotExist(err) {
+		t.Fatalf("Document saved to stale path")
+	}
+	if _, ok := server.sessions.Lookup("key-2"); ok {
+		t.Fatalf("Session not removed after save")
+	}
+}
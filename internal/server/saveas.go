@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"onlyoffice-fnos/internal/file"
+)
+
+var errForeignURL = errors.New("URL does not point to the Document Server")
+
+// SaveAsRequest represents a "Save Copy As" request posted by the editor page
+type SaveAsRequest struct {
+	Path     string `json:"path"`   // Path of the document being edited
+	Folder   string `json:"folder"` // Target folder, defaults to the folder of Path
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	FileType string `json:"fileType"`
+}
+
+// handleSaveAs handles POST /saveas
+// The editor posts the temporary Document Server URL from onRequestSaveAs,
+// the connector downloads it and writes a copy next to the original document.
+func (s *Server) handleSaveAs(w http.ResponseWriter, r *http.Request) {
+	var req SaveAsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	folder := req.Folder
+	if folder == "" && req.Path != "" {
+		folder = filepath.Dir(req.Path)
+	}
+	if folder == "" || req.URL == "" {
+		s.respondError(w, http.StatusBadRequest, "Folder and URL are required")
+		return
+	}
+
+	// Validate the target folder against the file access rules
+	if err := s.fileService.CheckDir(folder); err != nil {
+		log.Printf("Save as error: invalid folder %s: %v", folder, err)
+		switch err {
+		case file.ErrPermissionDenied:
+			s.respondError(w, http.StatusForbidden, "Permission denied")
+		default:
+			s.respondError(w, http.StatusBadRequest, "Invalid target folder")
+		}
+		return
+	}
+
+	name, ok := saveAsName(req.Title, req.FileType)
+	if !ok {
+		s.respondError(w, http.StatusBadRequest, "Invalid file name")
+		return
+	}
+	if userID, _ := s.requestUser(w, r); !s.canWrite(userID, filepath.Join(folder, name)) {
+		log.Printf("Policy denies %s saving a copy in %s", userID, folder)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	// Only fetch files from the Document Server
+	sourceURL, err := s.documentServerFileURL(req.URL)
+	if err != nil {
+		log.Printf("Save as error: %v: %s", err, req.URL)
+		s.respondError(w, http.StatusBadRequest, "Invalid document URL")
+		return
+	}
+
+	targetPath, err := s.fileService.UniquePath(filepath.Join(folder, name))
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid target path")
+		return
+	}
+
+	if err := s.saveDocument(targetPath, sourceURL); err != nil {
+		log.Printf("Save as error: %v", err)
+		s.respondError(w, http.StatusInternalServerError, "Failed to save copy")
+		return
+	}
+
+	log.Printf("Saved copy of %s as %s", req.Path, targetPath)
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"path":    targetPath,
+		"name":    filepath.Base(targetPath),
+	})
+}
+
+// saveAsName derives a safe file name from the editor title and file type
+func saveAsName(title, fileType string) (string, bool) {
+	name := strings.TrimSpace(filepath.Base(strings.ReplaceAll(title, `\`, "/")))
+	if name == "" || name == "." || name == "/" || strings.HasPrefix(name, ".") {
+		return "", false
+	}
+
+	fileType = strings.ToLower(strings.TrimPrefix(fileType, "."))
+	if fileType != "" && !strings.EqualFold(filepath.Ext(name), "."+fileType) {
+		name += "." + fileType
+	}
+	return name, true
+}
+
+// documentServerFileURL maps a browser-facing Document Server URL to the internal
+// Document Server URL. URLs that do not belong to the Document Server are rejected.
+func (s *Server) documentServerFileURL(raw string) (string, error) {
+	if s.settings == nil || s.settings.DocumentServerURL == "" {
+		return "", fmt.Errorf("document server URL not configured")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	internal := strings.TrimSuffix(s.settings.DocumentServerURL, "/")
+	if strings.HasPrefix(raw, internal+"/") {
+		return raw, nil
+	}
+
+	prefix := strings.TrimSuffix(s.getDocServerFrontendPath(), "/") + "/"
+	if u.IsAbs() && s.settings.DocumentServerPubURL != "" {
+		if pub := strings.TrimSuffix(s.settings.DocumentServerPubURL, "/"); strings.HasPrefix(raw, pub+"/") {
+			return internal + strings.TrimPrefix(raw, pub), nil
+		}
+	}
+	if !strings.HasPrefix(u.Path, prefix) {
+		return "", errForeignURL
+	}
+
+	rewritten := internal + "/" + strings.TrimPrefix(u.EscapedPath(), prefix)
+	if u.RawQuery != "" {
+		rewritten += "?" + u.RawQuery
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"onlyoffice-fnos/internal/config"
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/format"
+	"onlyoffice-fnos/internal/jwt"
+)
+
+// Test Save As downloads from the Document Server with collision-safe naming
+func TestSaveAs(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1", "docs"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "private"), 0755)
+
+	var requested string
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+		w.Write([]byte("copy"))
+	}))
+	defer mockDocServer.Close()
+
+	fileService := file.NewService(tempDir, 0)
+	fileService.SetAllowedRoots([]string{"/vol1"})
+
+	server := New(&Config{
+		Settings: &config.Settings{
+			DocumentServerURL: mockDocServer.URL,
+			DocServerPath:     "/doc-svr",
+		},
+		FileService:   fileService,
+		FormatManager: format.NewManager(),
+		JWTManager:    jwt.NewManager(),
+		BaseURL:       "http://localhost:10099",
+	})
+
+	saveAs := func(req SaveAsRequest) (*httptest.ResponseRecorder, map[string]interface{}) {
+		body, _ := json.Marshal(req)
+		httpReq := httptest.NewRequest("POST", "/saveas", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httpReq)
+
+		var resp map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec, resp
+	}
+
+	req := SaveAsRequest{
+		Path:     "/vol1/docs/report.docx",
+		Title:    "report copy",
+		URL:      "http://nas.local:9080/doc-svr/cache/files/data/output.docx?md5=abc",
+		FileType: "docx",
+	}
+
+	rec, resp := saveAs(req)
+	if rec.Code != http.StatusOK || resp["path"] != "/vol1/docs/report copy.docx" {
+		t.Fatalf("Unexpected response %d %v", rec.Code, resp)
+	}
+	if requested != "/cache/files/data/output.docx?md5=abc" {
+		t.Fatalf("Document Server URL not rewritten, requested %q", requested)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tempDir, "vol1", "docs", "report copy.docx")); string(data) != "copy" {
+		t.Fatalf("Unexpected saved content %q", data)
+	}
+
+	// Existing files are never overwritten
+	if _, resp = saveAs(req); resp["path"] != "/vol1/docs/report copy (1).docx" {
+		t.Fatalf("Expected suffixed name, got %v", resp["path"])
+	}
+
+	// URLs outside the Document Server are rejected
+	foreign := req
+	foreign.URL = "http://169.254.169.254/latest/meta-data"
+	if rec, _ = saveAs(foreign); rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for foreign URL, got %d", rec.Code)
+	}
+
+	// Folders outside the allowed roots are rejected
+	outside := req
+	outside.Folder = "/private"
+	if rec, _ = saveAs(outside); rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for folder outside allowed roots, got %d", rec.Code)
+	}
+}
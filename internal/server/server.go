@@ -14,8 +14,16 @@ import (
 	"onlyoffice-fnos/internal/config"
 	"onlyoffice-fnos/internal/editor"
 	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/fileid"
 	"onlyoffice-fnos/internal/format"
 	"onlyoffice-fnos/internal/jwt"
+	"onlyoffice-fnos/internal/lru"
+	"onlyoffice-fnos/internal/notify"
+	"onlyoffice-fnos/internal/policy"
+	"onlyoffice-fnos/internal/session"
+	"onlyoffice-fnos/internal/thumbnail"
+	"onlyoffice-fnos/internal/userdata"
+	"onlyoffice-fnos/internal/users"
 	"onlyoffice-fnos/web"
 )
 
@@ -26,7 +34,17 @@ type Server struct {
 	fileService   *file.Service
 	formatManager *format.Manager
 	jwtManager    *jwt.Manager
+	keyring       *jwt.Keyring
 	configBuilder *editor.ConfigBuilder
+	notifier      *notify.Notifier
+	sessions      *session.Registry
+	userdata      *userdata.Store
+	customization *editor.Profile
+	policy        *policy.Policy
+	users         *users.Directory
+	fileids       *fileid.Index
+	thumbnails    *thumbnail.Cache
+	conversions   *lru.Cache
 	baseURL       string
 	templates     *templates
 }
@@ -37,6 +55,15 @@ type Config struct {
 	FileService   *file.Service
 	FormatManager *format.Manager
 	JWTManager    *jwt.Manager
+	Notifier      *notify.Notifier  // Optional, save errors are only logged if nil
+	Sessions      *session.Registry // Optional, an in-memory registry is used if nil
+	UserData      *userdata.Store   // Optional, an in-memory store is used if nil
+	Customization *editor.Profile   // Optional, connector defaults are used if nil
+	Policy        *policy.Policy    // Optional, all modes are allowed if nil
+	Users         *users.Directory  // Optional, an in-memory directory of seen users is used if nil
+	FileIDs       *fileid.Index     // Optional, an in-memory index is used if nil
+	Thumbnails    *thumbnail.Cache  // Optional, an in-memory cache is used if nil
+	Conversions   *lru.Cache        // Optional, conversions are not cached if nil
 	BaseURL       string
 }
 
@@ -48,8 +75,32 @@ func New(cfg *Config) *Server {
 		fileService:   cfg.FileService,
 		formatManager: cfg.FormatManager,
 		jwtManager:    cfg.JWTManager,
+		notifier:      cfg.Notifier,
+		sessions:      cfg.Sessions,
+		userdata:      cfg.UserData,
+		customization: cfg.Customization,
+		policy:        cfg.Policy,
+		users:         cfg.Users,
+		fileids:       cfg.FileIDs,
+		thumbnails:    cfg.Thumbnails,
+		conversions:   cfg.Conversions,
 		baseURL:       cfg.BaseURL,
 	}
+	if s.sessions == nil {
+		s.sessions = session.NewRegistry("")
+	}
+	if s.userdata == nil {
+		s.userdata = userdata.NewStore("")
+	}
+	if s.users == nil {
+		s.users = users.NewDirectory("")
+	}
+	if s.fileids == nil {
+		s.fileids = fileid.NewIndex("")
+	}
+	if s.thumbnails == nil {
+		s.thumbnails = thumbnail.NewCache("", thumbnail.DefaultWorkers, thumbnail.DefaultMaxBytes)
+	}
 
 	// Use baseURL from settings if available
 	if cfg.Settings != nil && cfg.Settings.BaseURL != "" {
@@ -60,6 +111,11 @@ func New(cfg *Config) *Server {
 		s.baseURL = cfg.BaseURL
 	}
 
+	// Signing secrets, including previous secrets still accepted during rotation
+	if cfg.Settings != nil {
+		s.keyring = jwt.NewKeyring(cfg.Settings.SecretKID, cfg.Settings.DocumentServerSecret, cfg.Settings.GetAcceptedSecrets())
+	}
+
 	// Create config builder
 	s.configBuilder = editor.NewConfigBuilder(cfg.FormatManager, cfg.JWTManager)
 
@@ -91,14 +147,43 @@ func (s *Server) setupRoutes() {
 		s.router.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))))
 	}
 
-	// Page routes
-	s.router.Get("/editor", s.handleEditorPage)
-	s.router.Get("/convert", s.handleConvertPage)
-
-	// Document Server integration routes
+	// Document Server integration routes, authorized by their tokens
 	s.router.Get("/download", s.handleDownload)
 	s.router.Post("/callback", s.handleCallback)
-	s.router.Post("/convert", s.handleConvert)
+	s.router.Get("/api/formats", s.handleFormats)
+
+	// Everything else acts for a user
+	s.router.Group(func(r chi.Router) {
+		r.Use(s.requireUser)
+
+		// Page routes
+		r.Get("/", s.handleHome)
+		r.Get("/browse", s.handleBrowse)
+		r.Post("/favorites", s.handleFavorite)
+		r.Get("/editor", s.handleEditorPage)
+		r.Get("/embed", s.handleEmbedPage)
+		r.Get("/convert", s.handleConvertPage)
+		r.Get("/new", s.handleNewPage)
+		r.Post("/new", s.handleNewDocument)
+
+		// Notification routes
+		r.Get("/recovery", s.handleRecoveryDownload)
+		r.Post("/notifications/ack", s.handleNotificationAck)
+
+		// Conversion and thumbnail routes
+		r.Post("/convert", s.handleConvert)
+		r.Get("/thumbnail", s.handleThumbnail)
+		r.Post("/thumbnail/warm", s.handleThumbnailWarm)
+
+		// Editor event routes
+		r.Post("/saveas", s.handleSaveAs)
+		r.Post("/rename", s.handleRename)
+		r.Get("/picker", s.handlePicker)
+		r.Post("/picker/select", s.handlePick)
+		r.Get("/api/users", s.handleUsers)
+		r.Post("/api/notify", s.handleSendNotify)
+		r.Post("/reference", s.handleReferenceData)
+	})
 }
 
 // Router returns the chi router for testing
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"onlyoffice-fnos/internal/file"
+)
+
+// sniffFileInfo checks the content of a file against its extension. For a
+// mislabelled file it returns a copy whose extension is the real type, so
+// that the editor and the conversion use it, and a warning for the user.
+func (s *Server) sniffFileInfo(fileInfo *file.FileInfo) (*file.FileInfo, string) {
+	detected, err := s.fileService.Sniff(fileInfo.Path)
+	if err != nil || len(detected) == 0 {
+		return fileInfo, ""
+	}
+	for _, ext := range detected {
+		if ext == fileInfo.Extension {
+			return fileInfo, ""
+		}
+	}
+
+	actual := *fileInfo
+	actual.Extension = s.formatManager.DetectType(fileInfo.Extension, detected)
+	log.Printf("Content of %s is %s, opening it as %s", fileInfo.Path, detected[0], actual.Extension)
+
+	warning := fmt.Sprintf("文件扩展名为 .%s，但内容是 %s 格式", fileInfo.Extension, strings.ToUpper(detected[0]))
+	if actual.Extension == fileInfo.Extension {
+		return &actual, warning + "，可能无法正常打开。"
+	}
+	return &actual, warning + fmt.Sprintf("，将按 %s 格式处理。", strings.ToUpper(actual.Extension))
+}
+
+// isEncrypted returns true if a file is password protected
+func (s *Server) isEncrypted(filePath string) bool {
+	encrypted, err := s.fileService.IsEncrypted(filePath)
+	return err == nil && encrypted
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test mislabelled files are opened and converted with the type of their content
+func TestMislabelledFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "letter.doc"), []byte(`{\rtf1\ansi Dear Bob}`), 0644)
+
+	var docx bytes.Buffer
+	w := zip.NewWriter(&docx)
+	f, _ := w.Create("[Content_Types].xml")
+	f.Write([]byte(`<Override ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>`))
+	w.Close()
+	os.WriteFile(filepath.Join(tempDir, "vol1", "report.doc"), docx.Bytes(), 0644)
+
+	conversions := 0
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			conversions++
+		}
+		w.Write([]byte("edited docx"))
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+	server.settings.BaseURL = "http://connector:10099"
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	// An RTF file named .doc is converted from RTF, with a warning
+	rec := get("/convert?path=/vol1/letter.doc")
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), "内容是 RTF 格式") || !strings.Contains(rec.Body.String(), ">rtf<") {
+		t.Errorf("Expected RTF warning on the convert page, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// A DOCX file named .doc opens directly as DOCX
+	rec = get("/editor?path=/vol1/report.doc")
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), `"fileType":"docx"`) {
+		t.Errorf("Expected the editor with fileType docx, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if strings.Contains(rec.Body.String(), "inplace=") {
+		t.Errorf("A DOCX file named .doc should not be saved back through a conversion")
+	}
+
+	// and is saved as the DOCX the editor returns
+	reqBody, _ := json.Marshal(&CallbackRequest{Key: "report-key", Status: StatusSaved, URL: mockDocServer.URL + "/edited.docx", Filetype: "docx"})
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/callback?path=%2Fvol1%2Freport.doc", bytes.NewReader(reqBody)))
+	if content, _ := os.ReadFile(filepath.Join(tempDir, "vol1", "report.doc")); string(content) != "edited docx" || conversions != 0 {
+		t.Errorf("Expected the edited DOCX to be saved as is, got %q after %d conversions", content, conversions)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+
+	"onlyoffice-fnos/internal/format"
+)
+
+// textSampleLen is the number of leading bytes used to detect the encoding
+// and the delimiter, and shown in the convert page preview
+const textSampleLen = 4096
+
+// textOptions are the encoding and the column separator passed to the
+// conversion API for csv and txt files. Zero fields use the defaults.
+type textOptions struct {
+	CodePage  int
+	Delimiter int
+}
+
+// TextPreview holds the import options of the convert page for csv and txt files
+type TextPreview struct {
+	Sample     string // Start of the file, base64 encoded and decoded in the browser
+	CodePage   int
+	Delimiter  int
+	Encodings  []format.Encoding
+	Delimiters []format.Delimiter // Empty for txt files
+}
+
+// isTextFormat returns true for the formats taking text options
+func isTextFormat(ext string) bool {
+	return ext == "csv" || ext == "txt"
+}
+
+// parseTextOptions reads the codePage and delimiter parameters, ignoring
+// unknown values. The delimiter only applies to csv files.
+func parseTextOptions(values url.Values, ext string) textOptions {
+	var opts textOptions
+	if !isTextFormat(ext) {
+		return opts
+	}
+	if codePage, err := strconv.Atoi(values.Get("codePage")); err == nil && format.ValidCodePage(codePage) {
+		opts.CodePage = codePage
+	}
+	if delimiter, err := strconv.Atoi(values.Get("delimiter")); err == nil && ext == "csv" && format.ValidDelimiter(delimiter) {
+		opts.Delimiter = delimiter
+	}
+	return opts
+}
+
+// values returns the options as query parameters
+func (o textOptions) values() url.Values {
+	values := url.Values{}
+	if o.CodePage != 0 {
+		values.Set("codePage", strconv.Itoa(o.CodePage))
+	}
+	if o.Delimiter != 0 {
+		values.Set("delimiter", strconv.Itoa(o.Delimiter))
+	}
+	return values
+}
+
+// textOptionsFor completes the options given in the request with the ones
+// detected from the start of the file
+func (s *Server) textOptionsFor(values url.Values, filePath, ext string) textOptions {
+	opts := parseTextOptions(values, ext)
+	if !isTextFormat(ext) || opts.CodePage != 0 && (opts.Delimiter != 0 || ext != "csv") {
+		return opts
+	}
+	sample, err := s.fileService.Head(filePath, textSampleLen)
+	if err != nil {
+		return opts
+	}
+	detected := detectTextOptions(sample, ext)
+	if opts.CodePage == 0 {
+		opts.CodePage = detected.CodePage
+	}
+	if opts.Delimiter == 0 {
+		opts.Delimiter = detected.Delimiter
+	}
+	return opts
+}
+
+// detectTextOptions guesses the encoding, and the delimiter of csv files
+func detectTextOptions(sample []byte, ext string) textOptions {
+	opts := textOptions{CodePage: format.DetectCodePage(sample)}
+	if ext == "csv" {
+		opts.Delimiter = format.DetectDelimiter(sample)
+	}
+	return opts
+}
+
+// textPreview builds the import options of the convert page, nil for other formats
+func (s *Server) textPreview(filePath, ext string) *TextPreview {
+	if !isTextFormat(ext) {
+		return nil
+	}
+	sample, err := s.fileService.Head(filePath, textSampleLen)
+	if err != nil {
+		return nil
+	}
+	opts := detectTextOptions(sample, ext)
+	preview := &TextPreview{
+		Sample:    base64.StdEncoding.EncodeToString(sample),
+		CodePage:  opts.CodePage,
+		Delimiter: opts.Delimiter,
+		Encodings: format.Encodings,
+	}
+	if ext == "csv" {
+		preview.Delimiters = format.Delimiters
+	}
+	return preview
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"onlyoffice-fnos/internal/file"
+	"onlyoffice-fnos/internal/thumbnail"
+)
+
+// Thumbnail sizes in pixels, the size bounds both width and height
+const (
+	defaultThumbnailSize = 256
+	minThumbnailSize     = 16
+	maxThumbnailSize     = 1024
+)
+
+// maxThumbnailBytes limits the rendered image read from the Document Server
+const maxThumbnailBytes = 8 << 20
+
+// browseThumbnailSize is the size of the thumbnails shown in the file browser
+const browseThumbnailSize = 64
+
+// thumbnailURLExpiry is the lifetime of thumbnail URLs handed to the browser
+const thumbnailURLExpiry = time.Hour
+
+// thumbnailTypes maps the supported output formats to content types
+var thumbnailTypes = map[string]string{
+	"png": "image/png",
+	"jpg": "image/jpeg",
+}
+
+var (
+	errThumbnailSize   = errors.New("invalid thumbnail size")
+	errThumbnailFormat = errors.New("invalid thumbnail format")
+	errNoThumbnail     = errors.New("file has no thumbnail")
+)
+
+// ThumbnailOptions asks the conversion API for an image of the first page
+type ThumbnailOptions struct {
+	Aspect int  `json:"aspect"` // 1 keeps the aspect ratio within width and height
+	First  bool `json:"first"`  // Only the first page, as a single image instead of a zip
+	Width  int  `json:"width"`
+	Height int  `json:"height"`
+}
+
+// thumbnailRequest is a thumbnail of one file
+type thumbnailRequest struct {
+	fileInfo *file.FileInfo
+	size     int
+	format   string
+	key      string
+}
+
+// handleThumbnail handles GET /thumbnail - returns an image of the first page,
+// slide or sheet of a document
+func (s *Server) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		s.respondError(w, http.StatusBadRequest, "File path is required")
+		return
+	}
+
+	// A thumbnail shows the content, it needs the same token as a download
+	if s.jwtEnabled() {
+		if err := s.verifyDownload(r, filePath); err != nil {
+			log.Printf("Thumbnail error: invalid JWT token for %s: %v", filePath, err)
+			s.respondError(w, http.StatusForbidden, "Invalid token")
+			return
+		}
+	}
+
+	fileInfo, err := s.fileService.GetFileInfo(filePath)
+	if err != nil {
+		log.Printf("Thumbnail error: failed to get file info for %s: %v", filePath, err)
+		switch err {
+		case file.ErrFileNotFound:
+			s.respondError(w, http.StatusNotFound, "File not found")
+		case file.ErrInvalidPath:
+			s.respondError(w, http.StatusBadRequest, "Invalid file path")
+		case file.ErrPermissionDenied:
+			s.respondError(w, http.StatusForbidden, "Permission denied")
+		default:
+			s.respondError(w, http.StatusInternalServerError, "Failed to get file info")
+		}
+		return
+	}
+	if userID, _ := s.requestUser(w, r); !s.canRead(userID, filePath) {
+		log.Printf("Policy denies %s access to %s", userID, filePath)
+		s.respondError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+
+	req, err := s.newThumbnailRequest(fileInfo, r.URL.Query().Get("size"), r.URL.Query().Get("format"))
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	etag := `"` + req.key + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, err := s.thumbnail(req)
+	if err != nil {
+		log.Printf("Thumbnail error: %s: %v", filePath, err)
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+		if err == errNoThumbnail {
+			s.respondError(w, http.StatusUnsupportedMediaType, "File has no thumbnail")
+			return
+		}
+		s.respondError(w, http.StatusBadGateway, "Failed to render thumbnail")
+		return
+	}
+
+	w.Header().Set("Content-Type", thumbnailTypes[req.format])
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// handleThumbnailWarm handles POST /thumbnail/warm - renders the missing
+// thumbnails of a folder in the background
+func (s *Server) handleThumbnailWarm(w http.ResponseWriter, r *http.Request) {
+	folder := r.FormValue("folder")
+	if folder == "" {
+		s.respondError(w, http.StatusBadRequest, "Folder is required")
+		return
+	}
+
+	// Each request may queue many conversions, it needs a token for the folder
+	if s.jwtEnabled() {
+		if err := s.verifyDownload(r, folder); err != nil {
+			log.Printf("Thumbnail error: invalid JWT token for %s: %v", folder, err)
+			s.respondError(w, http.StatusForbidden, "Invalid token")
+			return
+		}
+	}
+
+	entries, err := s.fileService.ListDir(folder)
+	if err != nil {
+		log.Printf("Thumbnail error: failed to list %s: %v", folder, err)
+		switch err {
+		case file.ErrFileNotFound, file.ErrInvalidPath:
+			s.respondError(w, http.StatusNotFound, "Folder not found")
+		case file.ErrPermissionDenied:
+			s.respondError(w, http.StatusForbidden, "Permission denied")
+		default:
+			s.respondError(w, http.StatusInternalServerError, "Failed to list folder")
+		}
+		return
+	}
+
+	userID, _ := s.requestUser(w, r)
+	var pending []*thumbnailRequest
+	for _, entry := range entries {
+		if entry.IsDir || !s.canRead(userID, entry.Path) {
+			continue
+		}
+		if _, ok := s.formatManager.GetFormat(entry.Extension); !ok {
+			continue
+		}
+		req, err := s.newThumbnailRequest(entry, r.FormValue("size"), r.FormValue("format"))
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !s.thumbnails.Has(req.key) {
+			pending = append(pending, req)
+		}
+	}
+
+	// Render in the background through the bounded queue of the cache
+	queued := 0
+	for _, req := range pending {
+		req := req
+		if !s.thumbnails.Warm(req.key, func() ([]byte, error) {
+			data, err := s.renderThumbnail(req)
+			if err != nil {
+				log.Printf("Thumbnail error: %s: %v", req.fileInfo.Path, err)
+			}
+			return data, err
+		}) {
+			log.Printf("Thumbnail queue full, skipping %d thumbnails in %s", len(pending)-queued, folder)
+			break
+		}
+		queued++
+	}
+	log.Printf("Rendering %d thumbnails in %s", queued, folder)
+
+	s.respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"queued":  queued,
+	})
+}
+
+// signedThumbnailURL builds the URL of a file thumbnail, or of the warm
+// request of a folder, that carries its own token
+func (s *Server) signedThumbnailURL(endpoint, param, path string, size int) (string, error) {
+	query := url.Values{param: {path}, "size": {strconv.Itoa(size)}}
+	if s.jwtEnabled() {
+		token, err := s.signTokenWithExpiry(map[string]interface{}{"path": path}, thumbnailURLExpiry)
+		if err != nil {
+			return "", err
+		}
+		query.Set("token", token)
+	}
+	return endpoint + "?" + query.Encode(), nil
+}
+
+// newThumbnailRequest validates the size and the image format, empty for the defaults
+func (s *Server) newThumbnailRequest(fileInfo *file.FileInfo, size, format string) (*thumbnailRequest, error) {
+	req := &thumbnailRequest{fileInfo: fileInfo, size: defaultThumbnailSize, format: "png"}
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < minThumbnailSize || n > maxThumbnailSize {
+			return nil, errThumbnailSize
+		}
+		req.size = n
+	}
+	if format != "" {
+		if _, ok := thumbnailTypes[format]; !ok {
+			return nil, errThumbnailFormat
+		}
+		req.format = format
+	}
+	// Relative and absolute forms of a path share thumbnails
+	req.key = thumbnail.Key(filepath.Clean("/"+fileInfo.Path), fileInfo.ModTime, req.size, req.format)
+	return req, nil
+}
+
+// thumbnail returns a cached thumbnail or renders it with the conversion API
+func (s *Server) thumbnail(req *thumbnailRequest) ([]byte, error) {
+	return s.thumbnails.Get(req.key, func() ([]byte, error) {
+		return s.renderThumbnail(req)
+	})
+}
+
+// renderThumbnail renders a thumbnail with the conversion API
+func (s *Server) renderThumbnail(req *thumbnailRequest) ([]byte, error) {
+	// Render mislabelled files from the type of their content
+	fileInfo, _ := s.sniffFileInfo(req.fileInfo)
+	if _, ok := s.formatManager.GetFormat(fileInfo.Extension); !ok || s.isEncrypted(fileInfo.Path) {
+		return nil, errNoThumbnail
+	}
+
+	// Thumbnails are cached by the thumbnail cache only
+	content, err := s.convertDocument(&ConvertRequest{
+		Filetype:   fileInfo.Extension,
+		Key:        req.key,
+		Outputtype: req.format,
+		Title:      fileInfo.Name,
+		URL:        s.buildDownloadURL(fileInfo.Path),
+		Thumbnail: &ThumbnailOptions{
+			Aspect: 1,
+			First:  true,
+			Width:  req.size,
+			Height: req.size,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(io.LimitReader(content, maxThumbnailBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxThumbnailBytes {
+		return nil, fmt.Errorf("thumbnail larger than %d bytes", maxThumbnailBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"onlyoffice-fnos/internal/jwt"
+)
+
+// Test thumbnails are rendered with the conversion API, cached and pre-warmed for a folder
+func TestThumbnail(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "report.docx"), []byte("docx"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "slides.pptx"), []byte("pptx"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "notes.bin"), []byte("bin"), 0644)
+
+	var mu sync.Mutex
+	var conversions []ConvertRequest
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			var conversion ConvertRequest
+			json.NewDecoder(r.Body).Decode(&conversion)
+			mu.Lock()
+			conversions = append(conversions, conversion)
+			mu.Unlock()
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/thumb"})
+			return
+		}
+		w.Write([]byte("\x89PNG image"))
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	converted := func() []ConvertRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]ConvertRequest(nil), conversions...)
+	}
+
+	rec := get("/thumbnail?path=report.docx&size=128")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" || rec.Body.String() != "\x89PNG image" {
+		t.Fatalf("Expected a PNG thumbnail, got %d %s", rec.Code, rec.Body.String())
+	}
+	first := converted()
+	if len(first) != 1 {
+		t.Fatalf("Expected one conversion, got %d", len(first))
+	}
+	thumb := first[0].Thumbnail
+	if first[0].Outputtype != "png" || first[0].Filetype != "docx" || thumb == nil || !thumb.First || thumb.Width != 128 || thumb.Height != 128 {
+		t.Errorf("Unexpected conversion request %+v %+v", first[0], thumb)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if rec := get("/thumbnail?path=report.docx&size=128"); rec.Code != http.StatusOK || len(converted()) != 1 {
+		t.Errorf("Expected the cached thumbnail, got %d after %d conversions", rec.Code, len(converted()))
+	}
+	if rec := get("/thumbnail?path=report.docx&size=128", "If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", rec.Code)
+	}
+	if rec := get("/thumbnail?path=report.docx&size=128&format=jpg"); rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("Expected a JPEG thumbnail, got %q", rec.Header().Get("Content-Type"))
+	}
+
+	// A modified file is rendered again
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(tempDir, "report.docx"), later, later)
+	get("/thumbnail?path=report.docx&size=128")
+	if len(converted()) != 3 {
+		t.Errorf("Expected a new render for the modified file, got %d conversions", len(converted()))
+	}
+
+	for path, want := range map[string]int{
+		"/thumbnail?path=missing.docx":           http.StatusNotFound,
+		"/thumbnail?path=report.docx&size=4096":  http.StatusBadRequest,
+		"/thumbnail?path=report.docx&format=gif": http.StatusBadRequest,
+		"/thumbnail?path=notes.bin":              http.StatusUnsupportedMediaType,
+	} {
+		if rec := get(path); rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+
+	// Pre-warming renders the missing thumbnails of the folder
+	req := httptest.NewRequest("POST", "/thumbnail/warm?folder=/&size=128", nil)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"queued":1`) {
+		t.Fatalf("Expected one thumbnail to be queued, got %d %s", rec.Code, rec.Body.String())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !server.thumbnails.Has(mustThumbnailKey(t, server, "/slides.pptx", 128)) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the folder thumbnails to be rendered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Test thumbnails require the same token as downloads when JWT is enabled
+func TestThumbnailWithJWT(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "report.docx"), []byte("docx"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "salaries.xlsx"), []byte("xlsx"), 0644)
+
+	mockDocServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ConvertService.ashx" {
+			json.NewEncoder(w).Encode(&ConvertResponse{EndConvert: true, Percent: 100, FileURL: "http://" + r.Host + "/thumb"})
+			return
+		}
+		w.Write([]byte("\x89PNG image"))
+	}))
+	defer mockDocServer.Close()
+
+	server := createTestServer(t, tempDir)
+	server.settings.DocumentServerURL = mockDocServer.URL
+	server.settings.DocumentServerSecret = server.jwtManager.GenerateSecret()
+	server.keyring = jwt.NewKeyring("", server.settings.DocumentServerSecret, nil)
+
+	signed, err := server.signedDownloadURL("report.docx", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to sign URL: %v", err)
+	}
+	parsed, _ := url.Parse(signed)
+	token := url.QueryEscape(parsed.Query().Get("token"))
+
+	for path, want := range map[string]int{
+		"/thumbnail?path=report.docx":                  http.StatusForbidden,
+		"/thumbnail?path=report.docx&token=" + token:   http.StatusOK,
+		"/thumbnail?path=salaries.xlsx&token=" + token: http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+
+	// Warming a folder needs a token for the folder
+	warmURL, err := server.signedThumbnailURL("/thumbnail/warm", "folder", "/", 64)
+	if err != nil {
+		t.Fatalf("Failed to sign URL: %v", err)
+	}
+	for path, want := range map[string]int{
+		"/thumbnail/warm?folder=/":                http.StatusForbidden,
+		"/thumbnail/warm?folder=/&token=" + token: http.StatusForbidden,
+		warmURL: http.StatusAccepted,
+		strings.Replace(warmURL, "%2F", "%2Fvol1", 1): http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+
+	// The file browser hands out signed thumbnail URLs
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/browse?folder=/", nil))
+	thumbnailURL := regexp.MustCompile(`src="(/thumbnail\?[^"]+)"`).FindStringSubmatch(rec.Body.String())
+	if thumbnailURL == nil || !strings.Contains(rec.Body.String(), `hx-post="/thumbnail/warm?`) {
+		t.Fatalf("Expected thumbnail and warm URLs in the listing, got %s", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", html.UnescapeString(thumbnailURL[1]), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the listed thumbnail URL to be accepted, got %d", rec.Code)
+	}
+}
+
+// mustThumbnailKey returns the cache key of the PNG thumbnail of a file
+func mustThumbnailKey(t *testing.T, server *Server, path string, size int) string {
+	fileInfo, err := server.fileService.GetFileInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := server.newThumbnailRequest(fileInfo, strconv.Itoa(size), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req.key
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+
+	"onlyoffice-fnos/internal/notify"
+)
+
+// UserEntry is a user as expected by docEditor.setUsers
+type UserEntry struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"` // Mention handle, the user ID if no address is known
+}
+
+// UsersResponse is the response of GET /api/users
+type UsersResponse struct {
+	C     string       `json:"c"`
+	Users []*UserEntry `json:"users"`
+}
+
+// NotifyRequest is sent by the editor page for onRequestSendNotify
+type NotifyRequest struct {
+	Path       string          `json:"path"`
+	Key        string          `json:"key"` // Editing session the comment was written in
+	Message    string          `json:"message"`
+	Emails     []string        `json:"emails"`
+	ActionLink json.RawMessage `json:"actionLink,omitempty"`
+}
+
+// handleUsers handles GET /api/users - lists users for onRequestUsers.
+// The "c" parameter is the editor operation (mention, protect, info),
+// "q" filters the users and repeated "id" parameters select users by ID.
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	userID, _ := s.requestUser(w, r)
+	query := r.URL.Query()
+	c := query.Get("c")
+
+	ids := make(map[string]bool)
+	for _, id := range query["id"] {
+		ids[id] = true
+	}
+
+	resp := &UsersResponse{C: c, Users: []*UserEntry{}}
+	for _, u := range s.users.Search(query.Get("q")) {
+		if len(ids) > 0 && !ids[u.ID] {
+			continue
+		}
+		// Users do not mention themselves
+		if c == "mention" && u.ID == userID {
+			continue
+		}
+		resp.Users = append(resp.Users, &UserEntry{ID: u.ID, Name: u.Name, Email: u.Handle()})
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// handleSendNotify handles POST /api/notify - notifies the users mentioned in a comment
+func (s *Server) handleSendNotify(w http.ResponseWriter, r *http.Request) {
+	if s.notifier == nil {
+		s.respondError(w, http.StatusNotFound, "Notifications are not enabled")
+		return
+	}
+
+	var req NotifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Path == "" || len(req.Emails) == 0 {
+		s.respondError(w, http.StatusBadRequest, "File path and recipients are required")
+		return
+	}
+	if err := s.fileService.CheckAccess(req.Path); err != nil || !s.fileService.Exists(req.Path) {
+		s.respondError(w, http.StatusNotFound, "File not found")
+		return
+	}
+	if sess, ok := s.sessions.Lookup(req.Key); !ok || sess.Path != req.Path {
+		s.respondError(w, http.StatusForbidden, "Editing session required")
+		return
+	}
+
+	_, userName := s.requestUser(w, r)
+	ev := &notify.Event{
+		Kind:    notify.KindMention,
+		Path:    req.Path,
+		From:    userName,
+		Message: req.Message,
+		Link:    s.mentionLink(req.Path, req.ActionLink),
+	}
+	for _, handle := range req.Emails {
+		u, ok := s.users.Lookup(handle)
+		if !ok {
+			log.Printf("Mention of unknown user %s in %s", handle, req.Path)
+			s.respondError(w, http.StatusBadRequest, "Unknown recipient: "+handle)
+			return
+		}
+		ev.Users = append(ev.Users, u.ID)
+		if u.Email != "" {
+			ev.Emails = append(ev.Emails, u.Email)
+		}
+	}
+
+	if err := s.notifier.Report(ev, nil); err != nil {
+		log.Printf("Failed to record mention in %s: %v", req.Path, err)
+		s.respondError(w, http.StatusInternalServerError, "Failed to send notification")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{"success": true, "recipients": len(ev.Users)})
+}
+
+// mentionLink returns the editor URL that opens a document at the commented text
+func (s *Server) mentionLink(filePath string, actionLink json.RawMessage) string {
+	link := s.getEffectiveBaseURL() + "/editor?path=" + url.QueryEscape(filePath)
+	if len(actionLink) > 0 {
+		link += "&action=" + url.QueryEscape(string(actionLink))
+	}
+	return link
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"onlyoffice-fnos/internal/notify"
+	"onlyoffice-fnos/internal/users"
+)
+
+// Test the user list for mentions merges the users file with trusted header users
+func TestUsersForMentions(t *testing.T) {
+	tempDir := t.TempDir()
+	usersFile := filepath.Join(tempDir, "users.json")
+	os.WriteFile(usersFile, []byte(`[{"id": "alice", "name": "Alice", "email": "alice@nas.local"}, {"id": "bob", "name": "Bob"}]`), 0644)
+
+	server := createTestServer(t, tempDir)
+	server.users = users.NewDirectory("", &users.FileSource{Path: usersFile})
+	server.settings.UserHeader = "X-Forwarded-User"
+	server.settings.UserNameHeader = "X-Forwarded-Name"
+
+	list := func(query string, user string) *UsersResponse {
+		req := httptest.NewRequest("GET", "/api/users?"+query, nil)
+		req.Header.Set("X-Forwarded-User", user)
+		if user == "carol" {
+			req.Header.Set("X-Forwarded-Name", "Carol")
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		var resp UsersResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		return &resp
+	}
+
+	// Carol is remembered from the proxy headers and does not see herself
+	resp := list("c=mention", "carol")
+	if resp.C != "mention" || len(resp.Users) != 2 || resp.Users[0].Email != "alice@nas.local" || resp.Users[1].Email != "bob" {
+		t.Errorf("Unexpected mention list %+v", resp.Users)
+	}
+
+	resp = list("c=protect&q=car", "alice")
+	if len(resp.Users) != 1 || resp.Users[0].ID != "carol" || resp.Users[0].Name != "Carol" {
+		t.Errorf("Expected the remembered proxy user, got %+v", resp.Users)
+	}
+
+	if resp = list("c=info&id=bob", "alice"); len(resp.Users) != 1 || resp.Users[0].ID != "bob" {
+		t.Errorf("Expected users selected by ID, got %+v", resp.Users)
+	}
+}
+
+// Test a mention is recorded for the recipient and links to the comment
+func TestSendNotify(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "vol1"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "vol1", "a.docx"), []byte("doc"), 0644)
+
+	notifier, err := notify.NewNotifier(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+	server := createTestServer(t, tempDir)
+	server.settings.BaseURL = "http://connector:10099"
+	server.notifier = notifier
+	server.users.Remember(users.User{ID: "bob", Name: "Bob", Email: "bob@nas.local"})
+	server.sessions.Register("a-key", "/vol1/a.docx", time.Now())
+
+	send := func(req *NotifyRequest) int {
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/api/notify?user_id=alice", bytes.NewReader(body)))
+		return rec.Code
+	}
+	if code := send(&NotifyRequest{Path: "/vol1/a.docx", Message: "hi", Emails: []string{"bob@nas.local"}}); code != 403 {
+		t.Errorf("Expected 403 without an editing session, got %d", code)
+	}
+	if code := send(&NotifyRequest{Path: "/vol1/a.docx", Key: "a-key", Message: "hi", Emails: []string{"bob@nas.local", "mallory@example.com"}}); code != 400 {
+		t.Errorf("Expected 400 for an unknown recipient, got %d", code)
+	}
+	if pending := notifier.PendingFor("/vol1/a.docx", "bob"); len(pending) != 0 {
+		t.Fatalf("Rejected mentions should not be recorded: %+v", pending)
+	}
+
+	body, _ := json.Marshal(&NotifyRequest{
+		Path:       "/vol1/a.docx",
+		Key:        "a-key",
+		Message:    "@Bob please check",
+		Emails:     []string{"bob@nas.local"},
+		ActionLink: json.RawMessage(`{"action":{"type":"comment","data":"c1"}}`),
+	})
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("POST", "/api/notify?user_id=alice&user_name=Alice", bytes.NewReader(body)))
+	if rec.Code != 200 {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	pending := notifier.PendingFor("/vol1/a.docx", "bob")
+	if len(pending) != 1 || pending[0].From != "Alice" || pending[0].Emails[0] != "bob@nas.local" {
+		t.Fatalf("Unexpected mention %+v", pending)
+	}
+	link, _ := url.Parse(pending[0].Link)
+	action := link.Query().Get("action")
+	if action == "" {
+		t.Fatalf("Expected action link in %s", pending[0].Link)
+	}
+
+	// Bob sees the notice and the editor scrolls to the comment
+	open := func(user string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/editor?path=%2Fvol1%2Fa.docx&user_id="+user+"&action="+url.QueryEscape(action), nil))
+		return rec.Body.String()
+	}
+	if body := open("bob"); !strings.Contains(body, "@Bob please check") || !strings.Contains(body, `"actionLink":{"action":{"data":"c1","type":"comment"}}`) {
+		t.Errorf("Expected mention notice and action link for bob: %s", body)
+	}
+	if body := open("carol"); strings.Contains(body, "@Bob please check") {
+		t.Errorf("Mention should not be shown to other users")
+	}
+}
@@ -0,0 +1,194 @@
+package session
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TTL is how long a session is kept without hearing from the Document Server.
+// Sessions whose closing callback was lost would otherwise be joined forever.
+const TTL = 24 * time.Hour
+
+// Session tracks an open Document Server editing session
+type Session struct {
+	Key       string    `json:"key"`
+	Path      string    `json:"path"`
+	ModTime   time.Time `json:"modTime"` // File version the session was opened or last saved at
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// stale returns true if the file changed outside the session or the
+// Document Server has not reported the session for longer than TTL
+func (s *Session) stale(modTime, now time.Time) bool {
+	return !s.ModTime.Equal(modTime) || now.Sub(s.LastSeen) > TTL
+}
+
+// Registry maps document keys to the file they currently belong to.
+// Callbacks are routed through it so that renamed files keep saving to
+// the right place, and new editors join the session already open for a file.
+type Registry struct {
+	mu        sync.Mutex
+	storePath string
+	sessions  map[string]*Session
+}
+
+// NewRegistry creates a Registry. If dataDir is not empty, sessions are
+// persisted so that callbacks still resolve after a connector restart.
+func NewRegistry(dataDir string) *Registry {
+	r := &Registry{
+		sessions: make(map[string]*Session),
+	}
+	if dataDir == "" {
+		return r
+	}
+
+	r.storePath = filepath.Join(dataDir, "sessions.json")
+	data, err := os.ReadFile(r.storePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Session registry: failed to read %s: %v", r.storePath, err)
+		}
+		return r
+	}
+	if err := json.Unmarshal(data, &r.sessions); err != nil {
+		log.Printf("Session registry: failed to parse %s: %v", r.storePath, err)
+		r.sessions = make(map[string]*Session)
+	}
+
+	now := time.Now()
+	for key, s := range r.sessions {
+		if now.Sub(s.LastSeen) > TTL {
+			log.Printf("Session registry: discarding expired session %s for %s", key, s.Path)
+			delete(r.sessions, key)
+		}
+	}
+	return r
+}
+
+// Register records that key is being edited for path at file version modTime
+func (r *Registry) Register(key, path string, modTime time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if s, ok := r.sessions[key]; ok && s.Path == path && s.ModTime.Equal(modTime) {
+		s.LastSeen = now
+	} else {
+		r.sessions[key] = &Session{Key: key, Path: path, ModTime: modTime, CreatedAt: now, LastSeen: now}
+	}
+	r.save()
+}
+
+// Touch records that the Document Server reported a session. A non-zero
+// modTime is the file version the session has just saved.
+func (r *Registry) Touch(key string, modTime time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.sessions[key]
+	if !ok {
+		return
+	}
+	s.LastSeen = time.Now()
+	if !modTime.IsZero() {
+		s.ModTime = modTime
+	}
+	r.save()
+}
+
+// Lookup returns the session for a document key
+func (r *Registry) Lookup(key string) (*Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.sessions[key]
+	if !ok {
+		return nil, false
+	}
+	result := *s
+	return &result, true
+}
+
+// KeyForPath returns the key of an open session for path at file version
+// modTime. Sessions of an older version or past TTL are discarded, their
+// Document Server copy no longer matches the file.
+func (r *Registry) KeyForPath(path string, modTime time.Time) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, s := range r.sessions {
+		if s.Path != path {
+			continue
+		}
+		if s.stale(modTime, now) {
+			log.Printf("Session registry: discarding stale session %s for %s", key, path)
+			delete(r.sessions, key)
+			r.save()
+			continue
+		}
+		return s.Key, true
+	}
+	return "", false
+}
+
+// Rename moves all sessions of oldPath to newPath
+func (r *Registry) Rename(oldPath, newPath string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.sessions {
+		if s.Path == oldPath {
+			s.Path = newPath
+		}
+	}
+	r.save()
+}
+
+// RemovePath forgets the sessions of a deleted file
+func (r *Registry) RemovePath(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := false
+	for key, s := range r.sessions {
+		if s.Path == path {
+			delete(r.sessions, key)
+			changed = true
+		}
+	}
+	if changed {
+		r.save()
+	}
+}
+
+// Remove forgets a session once the Document Server has closed it
+func (r *Registry) Remove(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[key]; !ok {
+		return
+	}
+	delete(r.sessions, key)
+	r.save()
+}
+
+// save persists the sessions, must be called with the lock held
+func (r *Registry) save() {
+	if r.storePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(r.sessions, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(r.storePath, data, 0644); err != nil {
+		log.Printf("Session registry: failed to write %s: %v", r.storePath, err)
+	}
+}
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+// Unit test: Sessions follow renames and survive a restart
+func TestRegistry(t *testing.T) {
+	dataDir := t.TempDir()
+	r := NewRegistry(dataDir)
+
+	modTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	r.Register("key1", "/vol1/a.docx", modTime)
+	if key, ok := r.KeyForPath("/vol1/a.docx", modTime); !ok || key != "key1" {
+		t.Fatalf("expected key1 for path, got %q %v", key, ok)
+	}
+
+	r.Rename("/vol1/a.docx", "/vol1/b.docx")
+	s, ok := r.Lookup("key1")
+	if !ok || s.Path != "/vol1/b.docx" {
+		t.Fatalf("session should follow rename, got %+v", s)
+	}
+	if _, ok := r.KeyForPath("/vol1/a.docx", modTime); ok {
+		t.Error("old path should no longer have a session")
+	}
+
+	reloaded := NewRegistry(dataDir)
+	if s, ok := reloaded.Lookup("key1"); !ok || s.Path != "/vol1/b.docx" {
+		t.Fatalf("session should be persisted, got %+v", s)
+	}
+
+	reloaded.Remove("key1")
+	if _, ok := reloaded.Lookup("key1"); ok {
+		t.Error("removed session should not be found")
+	}
+
+	reloaded.Register("key2", "/vol1/c.docx", modTime)
+	reloaded.RemovePath("/vol1/c.docx")
+	if _, ok := reloaded.KeyForPath("/vol1/c.docx", modTime); ok {
+		t.Error("sessions of a deleted file should be removed")
+	}
+}
+
+// Unit test: Sessions of a changed file or past their TTL are discarded
+func TestRegistryStale(t *testing.T) {
+	dataDir := t.TempDir()
+	r := NewRegistry(dataDir)
+	saved := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	r.Register("key1", "/vol1/a.docx", saved)
+	if _, ok := r.KeyForPath("/vol1/a.docx", saved.Add(time.Minute)); ok {
+		t.Fatal("session of an older file version should not be joined")
+	}
+	if _, ok := r.Lookup("key1"); ok {
+		t.Error("stale session should be discarded")
+	}
+
+	// A forcesave moves the session to the new version
+	r.Register("key2", "/vol1/b.docx", saved)
+	r.Touch("key2", saved.Add(time.Minute))
+	if key, ok := r.KeyForPath("/vol1/b.docx", saved.Add(time.Minute)); !ok || key != "key2" {
+		t.Fatalf("expected key2 after save, got %q %v", key, ok)
+	}
+
+	// A session not reported for longer than TTL is dropped, also on restart
+	r.Register("key3", "/vol1/c.docx", saved)
+	r.mu.Lock()
+	r.sessions["key3"].LastSeen = time.Now().Add(-TTL - time.Minute)
+	r.save()
+	r.mu.Unlock()
+	if _, ok := NewRegistry(dataDir).Lookup("key3"); ok {
+		t.Error("expired session should not be loaded")
+	}
+	if _, ok := r.KeyForPath("/vol1/c.docx", saved); ok {
+		t.Error("expired session should not be joined")
+	}
+}
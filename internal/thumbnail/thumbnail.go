@@ -0,0 +1,167 @@
+package thumbnail
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"onlyoffice-fnos/internal/lru"
+)
+
+// DefaultWorkers is the number of thumbnails rendered at the same time
+const DefaultWorkers = 2
+
+// DefaultMaxBytes is the default size limit of the cached thumbnails
+const DefaultMaxBytes = 64 << 20
+
+// warmQueueSize bounds the thumbnails waiting to be rendered in the background
+const warmQueueSize = 256
+
+// Cache keeps rendered thumbnails, evicting the least recently used ones
+// above a size limit. Each thumbnail is rendered once even if requested
+// concurrently, and only a fixed number are rendered at a time so that
+// browsing a folder does not flood the Document Server.
+type Cache struct {
+	mu       sync.Mutex
+	store    *lru.Cache
+	inflight map[string]*render
+	workers  chan struct{}
+	queue    chan *warm
+	start    sync.Once
+}
+
+// render is a thumbnail being rendered, shared by concurrent requests
+type render struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// warm is a thumbnail queued for rendering in the background
+type warm struct {
+	key string
+	fn  func() ([]byte, error)
+}
+
+// NewCache creates a Cache holding up to maxBytes of thumbnails, or
+// DefaultMaxBytes if maxBytes is not positive. If dataDir is not empty,
+// thumbnails are stored on disk below it; otherwise they are kept in memory.
+func NewCache(dataDir string, workers int, maxBytes int64) *Cache {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	dir := ""
+	if dataDir != "" {
+		dir = filepath.Join(dataDir, "thumbnails")
+	}
+	return &Cache{
+		store:    lru.New(dir, maxBytes),
+		inflight: make(map[string]*render),
+		workers:  make(chan struct{}, workers),
+		queue:    make(chan *warm, warmQueueSize),
+	}
+}
+
+// Key identifies a thumbnail of one version of a file. A modified file gets
+// a new key, so stale thumbnails are never served.
+func Key(path string, modTime time.Time, size int, format string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%s", path, modTime.UnixNano(), size, format)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns a cached thumbnail or renders it with fn
+func (c *Cache) Get(key string, fn func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if data, ok := c.load(key); ok {
+		c.mu.Unlock()
+		return data, nil
+	}
+	if r, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-r.done
+		return r.data, r.err
+	}
+	r := &render{done: make(chan struct{})}
+	c.inflight[key] = r
+	c.mu.Unlock()
+
+	c.workers <- struct{}{}
+	r.data, r.err = fn()
+	<-c.workers
+
+	c.mu.Lock()
+	if r.err == nil {
+		c.save(key, r.data)
+	}
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(r.done)
+	return r.data, r.err
+}
+
+// Warm queues a thumbnail for rendering in the background. It returns false
+// if the queue is full.
+func (c *Cache) Warm(key string, fn func() ([]byte, error)) bool {
+	c.start.Do(func() {
+		for i := 0; i < cap(c.workers); i++ {
+			go c.drain()
+		}
+	})
+	select {
+	case c.queue <- &warm{key: key, fn: fn}:
+		return true
+	default:
+		return false
+	}
+}
+
+// drain renders queued thumbnails, errors are left to fn
+func (c *Cache) drain() {
+	for w := range c.queue {
+		c.Get(w.key, w.fn)
+	}
+}
+
+// Has returns true if a thumbnail is cached
+func (c *Cache) Has(key string) bool {
+	return c.store.Has(key)
+}
+
+// Size returns the total size of the cached thumbnails
+func (c *Cache) Size() int64 {
+	return c.store.Size()
+}
+
+// load reads a cached thumbnail, the caller holds the lock
+func (c *Cache) load(key string) ([]byte, bool) {
+	content, ok := c.store.Open(key)
+	if !ok {
+		return nil, false
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// save caches a thumbnail, the caller holds the lock. Failing to write only
+// costs a render next time.
+func (c *Cache) save(key string, data []byte) {
+	content, err := c.store.Store(key, io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		log.Printf("Thumbnails: failed to store %s: %v", key, err)
+		return
+	}
+	content.Close()
+}
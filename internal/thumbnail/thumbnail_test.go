@@ -0,0 +1,152 @@
+package thumbnail
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Unit test: thumbnails are rendered once, survive a restart and change with the file
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCache(dir, 1, 0)
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	key := Key("/vol1/report.docx", modTime, 256, "png")
+
+	renders := 0
+	render := func() ([]byte, error) {
+		renders++
+		return []byte("image"), nil
+	}
+	for i := 0; i < 2; i++ {
+		data, err := c.Get(key, render)
+		if err != nil || string(data) != "image" {
+			t.Fatalf("Get() = %q, %v", data, err)
+		}
+	}
+	if renders != 1 {
+		t.Errorf("expected one render, got %d", renders)
+	}
+
+	reloaded := NewCache(dir, 1, 0)
+	if !reloaded.Has(key) {
+		t.Error("thumbnails should survive a restart")
+	}
+	if Key("/vol1/report.docx", modTime.Add(time.Second), 256, "png") == key {
+		t.Error("a modified file should get a new key")
+	}
+	if Key("/vol1/report.docx", modTime, 128, "png") == key || Key("/vol1/report.docx", modTime, 256, "jpg") == key {
+		t.Error("sizes and formats should get their own keys")
+	}
+
+	failed := Key("/vol1/broken.docx", modTime, 256, "png")
+	if _, err := c.Get(failed, func() ([]byte, error) { return nil, errors.New("conversion failed") }); err == nil {
+		t.Error("expected the render error")
+	}
+	if c.Has(failed) {
+		t.Error("failed renders should not be cached")
+	}
+}
+
+// Unit test: concurrent requests share one render and workers bound the renders
+func TestCacheConcurrency(t *testing.T) {
+	c := NewCache("", 2, 0)
+	var running, peak, renders int32
+	render := func() ([]byte, error) {
+		atomic.AddInt32(&renders, 1)
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return []byte("image"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		key := Key("/vol1/file", time.Time{}, 16*(i%3+1), "png")
+		for j := 0; j < 3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Get(key, render)
+			}()
+		}
+	}
+	wg.Wait()
+
+	if renders != 3 {
+		t.Errorf("expected one render per key, got %d", renders)
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 renders at a time, got %d", peak)
+	}
+}
+
+// Unit test: the least recently used thumbnails are evicted above the size limit
+func TestCacheLimit(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		c := NewCache(dir, 1, 10)
+		image := func() ([]byte, error) { return []byte("image"), nil }
+		a := Key("/vol1/a.docx", time.Time{}, 256, "png")
+		b := Key("/vol1/b.docx", time.Time{}, 256, "png")
+		d := Key("/vol1/d.docx", time.Time{}, 256, "png")
+
+		c.Get(a, image)
+		c.Get(b, image)
+		c.Get(a, image) // a is now more recent than b
+		c.Get(d, image)
+		if !c.Has(a) || c.Has(b) || !c.Has(d) {
+			t.Errorf("dir %q: expected b to be evicted, got a %v b %v d %v", dir, c.Has(a), c.Has(b), c.Has(d))
+		}
+		if c.Size() != 10 {
+			t.Errorf("dir %q: expected 10 cached bytes, got %d", dir, c.Size())
+		}
+		if dir == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "thumbnails", b)); !os.IsNotExist(err) {
+			t.Errorf("evicted thumbnail should be removed from disk: %v", err)
+		}
+		if reloaded := NewCache(dir, 1, 5); reloaded.Size() != 5 || !reloaded.Has(d) {
+			t.Errorf("a smaller limit should evict on startup, got %d bytes", reloaded.Size())
+		}
+	}
+}
+
+// Unit test: warming renders queued thumbnails in the background and refuses when the queue is full
+func TestCacheWarm(t *testing.T) {
+	c := NewCache("", 1, 0)
+	release := make(chan struct{})
+	blocked := func() ([]byte, error) {
+		<-release
+		return []byte("image"), nil
+	}
+
+	queued := 0
+	for i := 0; i < warmQueueSize+10; i++ {
+		if c.Warm(Key("/vol1/file", time.Time{}, i+1, "png"), blocked) {
+			queued++
+		}
+	}
+	if queued >= warmQueueSize+10 || queued < warmQueueSize {
+		t.Errorf("expected the queue to be bounded near %d, got %d", warmQueueSize, queued)
+	}
+	close(release)
+
+	key := Key("/vol1/file", time.Time{}, 1, "png")
+	for deadline := time.Now().Add(5 * time.Second); !c.Has(key) && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !c.Has(key) {
+		t.Error("queued thumbnails should be rendered")
+	}
+}
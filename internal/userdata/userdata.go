@@ -0,0 +1,204 @@
+package userdata
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MaxRecent is the number of recent documents kept per user
+const MaxRecent = 20
+
+// Entry is a document in a user's recent or favorites list
+type Entry struct {
+	Path string    `json:"path"`
+	Name string    `json:"name"`
+	Time time.Time `json:"time"` // Last opened for recent entries, added for favorites
+}
+
+// UserData holds the per-user lists
+type UserData struct {
+	Recent    []Entry `json:"recent,omitempty"`
+	Favorites []Entry `json:"favorites,omitempty"`
+}
+
+// Store keeps recent documents and favorites per user
+type Store struct {
+	mu        sync.Mutex
+	storePath string
+	users     map[string]*UserData
+}
+
+// NewStore creates a Store. If dataDir is not empty, the lists are persisted.
+func NewStore(dataDir string) *Store {
+	s := &Store{
+		users: make(map[string]*UserData),
+	}
+	if dataDir == "" {
+		return s
+	}
+
+	s.storePath = filepath.Join(dataDir, "userdata.json")
+	data, err := os.ReadFile(s.storePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("User data: failed to read %s: %v", s.storePath, err)
+		}
+		return s
+	}
+	if err := json.Unmarshal(data, &s.users); err != nil {
+		log.Printf("User data: failed to parse %s: %v", s.storePath, err)
+		s.users = make(map[string]*UserData)
+	}
+	return s
+}
+
+// AddRecent moves path to the top of the user's recent list
+func (s *Store) AddRecent(user, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.user(user)
+	recent := []Entry{{Path: path, Name: filepath.Base(path), Time: time.Now()}}
+	for _, e := range u.Recent {
+		if e.Path != path && len(recent) < MaxRecent {
+			recent = append(recent, e)
+		}
+	}
+	u.Recent = recent
+	s.save()
+}
+
+// Recent returns the user's recent documents, most recent first
+func (s *Store) Recent(user string) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[user]; ok {
+		return append([]Entry(nil), u.Recent...)
+	}
+	return nil
+}
+
+// ToggleFavorite adds or removes path from the user's favorites and
+// returns true if it is now a favorite
+func (s *Store) ToggleFavorite(user, path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.user(user)
+	for i, e := range u.Favorites {
+		if e.Path == path {
+			u.Favorites = append(u.Favorites[:i], u.Favorites[i+1:]...)
+			s.save()
+			return false
+		}
+	}
+	u.Favorites = append(u.Favorites, Entry{Path: path, Name: filepath.Base(path), Time: time.Now()})
+	s.save()
+	return true
+}
+
+// Favorites returns the user's favorites in the order they were added
+func (s *Store) Favorites(user string) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[user]; ok {
+		return append([]Entry(nil), u.Favorites...)
+	}
+	return nil
+}
+
+// IsFavorite returns true if path is one of the user's favorites
+func (s *Store) IsFavorite(user, path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[user]; ok {
+		for _, e := range u.Favorites {
+			if e.Path == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Rename updates the lists of all users after a file was renamed
+func (s *Store) Rename(oldPath, newPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for _, u := range s.users {
+		for _, list := range [][]Entry{u.Recent, u.Favorites} {
+			for i := range list {
+				if list[i].Path == oldPath {
+					list[i].Path = newPath
+					list[i].Name = filepath.Base(newPath)
+					changed = true
+				}
+			}
+		}
+	}
+	if changed {
+		s.save()
+	}
+}
+
+// Remove drops a deleted file from the lists of all users
+func (s *Store) Remove(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for _, u := range s.users {
+		recent, favorites := withoutPath(u.Recent, path), withoutPath(u.Favorites, path)
+		if len(recent) != len(u.Recent) || len(favorites) != len(u.Favorites) {
+			u.Recent, u.Favorites = recent, favorites
+			changed = true
+		}
+	}
+	if changed {
+		s.save()
+	}
+}
+
+// withoutPath returns the entries of list that are not path
+func withoutPath(list []Entry, path string) []Entry {
+	var result []Entry
+	for _, e := range list {
+		if e.Path != path {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// user returns the data of a user, must be called with the lock held
+func (s *Store) user(user string) *UserData {
+	u, ok := s.users[user]
+	if !ok {
+		u = &UserData{}
+		s.users[user] = u
+	}
+	return u
+}
+
+// save persists the lists, must be called with the lock held
+func (s *Store) save() {
+	if s.storePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s.users, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(s.storePath, data, 0644); err != nil {
+		log.Printf("User data: failed to write %s: %v", s.storePath, err)
+	}
+}
@@ -0,0 +1,77 @@
+package userdata
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Unit test: Recent list is ordered, deduplicated, capped and persisted per user
+func TestRecent(t *testing.T) {
+	dataDir := t.TempDir()
+	s := NewStore(dataDir)
+
+	s.AddRecent("alice", "/vol1/a.docx")
+	s.AddRecent("alice", "/vol1/b.xlsx")
+	s.AddRecent("alice", "/vol1/a.docx")
+	s.AddRecent("bob", "/vol1/c.pptx")
+
+	recent := s.Recent("alice")
+	if len(recent) != 2 || recent[0].Path != "/vol1/a.docx" || recent[1].Path != "/vol1/b.xlsx" {
+		t.Fatalf("unexpected recent list %+v", recent)
+	}
+	if recent[0].Name != "a.docx" {
+		t.Errorf("expected name a.docx, got %q", recent[0].Name)
+	}
+	if len(s.Recent("bob")) != 1 {
+		t.Errorf("users should have separate lists")
+	}
+
+	for i := 0; i < MaxRecent+5; i++ {
+		s.AddRecent("alice", fmt.Sprintf("/vol1/%d.docx", i))
+	}
+	if n := len(s.Recent("alice")); n != MaxRecent {
+		t.Errorf("expected %d recent entries, got %d", MaxRecent, n)
+	}
+
+	if n := len(NewStore(dataDir).Recent("alice")); n != MaxRecent {
+		t.Errorf("recent list should be persisted, got %d entries", n)
+	}
+}
+
+// Unit test: Favorites toggle and follow renames
+func TestFavorites(t *testing.T) {
+	s := NewStore(t.TempDir())
+
+	if !s.ToggleFavorite("alice", "/vol1/a.docx") || !s.IsFavorite("alice", "/vol1/a.docx") {
+		t.Fatal("expected a.docx to be a favorite")
+	}
+	if s.IsFavorite("bob", "/vol1/a.docx") {
+		t.Error("favorites should be per user")
+	}
+
+	s.AddRecent("alice", "/vol1/a.docx")
+	s.Rename("/vol1/a.docx", "/vol1/renamed.docx")
+	if !s.IsFavorite("alice", "/vol1/renamed.docx") || s.Recent("alice")[0].Name != "renamed.docx" {
+		t.Error("lists should follow renames")
+	}
+
+	if s.ToggleFavorite("alice", "/vol1/renamed.docx") || len(s.Favorites("alice")) != 0 {
+		t.Error("second toggle should remove the favorite")
+	}
+}
+
+// Unit test: Deleted files leave the recent and favorites lists
+func TestRemove(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.AddRecent("alice", "/vol1/a.docx")
+	s.AddRecent("alice", "/vol1/b.docx")
+	s.ToggleFavorite("bob", "/vol1/a.docx")
+
+	s.Remove("/vol1/a.docx")
+	if recent := s.Recent("alice"); len(recent) != 1 || recent[0].Path != "/vol1/b.docx" {
+		t.Errorf("unexpected recent list %+v", recent)
+	}
+	if s.IsFavorite("bob", "/vol1/a.docx") {
+		t.Error("deleted file should not stay a favorite")
+	}
+}
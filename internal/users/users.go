@@ -0,0 +1,252 @@
+package users
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MinPasswdUID is the lowest UID of regular accounts in a passwd file
+const MinPasswdUID = 1000
+
+// User is a person who can be mentioned in comments
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// Handle returns the address used to mention the user, the email if known,
+// otherwise the user ID
+func (u User) Handle() string {
+	if u.Email != "" {
+		return u.Email
+	}
+	return u.ID
+}
+
+// Source lists users from an external directory
+type Source interface {
+	Users() ([]User, error)
+}
+
+// FileSource reads users from a JSON file holding a list of users
+type FileSource struct {
+	Path string
+}
+
+// Users implements Source. A missing file yields no users.
+func (f *FileSource) Users() ([]User, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []User
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// PasswdSource reads the regular accounts of an /etc/passwd style file,
+// such as the one of the fnOS host
+type PasswdSource struct {
+	Path string
+}
+
+// Users implements Source. System accounts and accounts without a login shell are skipped.
+func (p *PasswdSource) Users() ([]User, error) {
+	f, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []User
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 7 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		uid, err := strconv.Atoi(fields[2])
+		if err != nil || uid < MinPasswdUID || uid == 65534 {
+			continue
+		}
+		if strings.HasSuffix(fields[6], "nologin") || strings.HasSuffix(fields[6], "false") {
+			continue
+		}
+
+		name := strings.TrimSpace(strings.Split(fields[4], ",")[0])
+		if name == "" {
+			name = fields[0]
+		}
+		list = append(list, User{ID: fields[0], Name: name})
+	}
+	return list, scanner.Err()
+}
+
+// Directory merges the users of its sources with the users seen by the
+// connector, for example through trusted proxy headers
+type Directory struct {
+	mu        sync.Mutex
+	sources   []Source
+	storePath string
+	seen      map[string]User
+}
+
+// NewDirectory creates a Directory. If dataDir is not empty, seen users are persisted.
+func NewDirectory(dataDir string, sources ...Source) *Directory {
+	d := &Directory{
+		sources: sources,
+		seen:    make(map[string]User),
+	}
+	if dataDir == "" {
+		return d
+	}
+
+	d.storePath = filepath.Join(dataDir, "known_users.json")
+	data, err := os.ReadFile(d.storePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Users: failed to read %s: %v", d.storePath, err)
+		}
+		return d
+	}
+	if err := json.Unmarshal(data, &d.seen); err != nil {
+		log.Printf("Users: failed to parse %s: %v", d.storePath, err)
+		d.seen = make(map[string]User)
+	}
+	return d
+}
+
+// Remember records a user seen by the connector. Known details are kept
+// when the new record leaves them empty.
+func (d *Directory) Remember(u User) {
+	if u.ID == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	old, ok := d.seen[u.ID]
+	if u.Name == "" {
+		u.Name = old.Name
+	}
+	if u.Email == "" {
+		u.Email = old.Email
+	}
+	if ok && old == u {
+		return
+	}
+	d.seen[u.ID] = u
+	d.save()
+}
+
+// List returns all users sorted by name. Sources take precedence over seen
+// users, missing details are filled in from later records.
+func (d *Directory) List() []User {
+	byID := make(map[string]User)
+	var order []string
+	add := func(u User) {
+		if u.ID == "" {
+			return
+		}
+		existing, ok := byID[u.ID]
+		if !ok {
+			byID[u.ID] = u
+			order = append(order, u.ID)
+			return
+		}
+		if existing.Name == "" {
+			existing.Name = u.Name
+		}
+		if existing.Email == "" {
+			existing.Email = u.Email
+		}
+		byID[u.ID] = existing
+	}
+
+	for _, source := range d.sources {
+		list, err := source.Users()
+		if err != nil {
+			log.Printf("Users: failed to list users: %v", err)
+			continue
+		}
+		for _, u := range list {
+			add(u)
+		}
+	}
+
+	d.mu.Lock()
+	for _, u := range d.seen {
+		add(u)
+	}
+	d.mu.Unlock()
+
+	result := make([]User, 0, len(order))
+	for _, id := range order {
+		u := byID[id]
+		if u.Name == "" {
+			u.Name = u.ID
+		}
+		result = append(result, u)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
+
+// Search returns the users whose ID, name or email contains query
+func (d *Directory) Search(query string) []User {
+	all := d.List()
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return all
+	}
+
+	var result []User
+	for _, u := range all {
+		if strings.Contains(strings.ToLower(u.ID), query) ||
+			strings.Contains(strings.ToLower(u.Name), query) ||
+			strings.Contains(strings.ToLower(u.Email), query) {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// Lookup returns the user with the given ID, email or mention handle
+func (d *Directory) Lookup(handle string) (User, bool) {
+	for _, u := range d.List() {
+		if u.ID == handle || (u.Email != "" && strings.EqualFold(u.Email, handle)) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// save persists the seen users, must be called with the lock held
+func (d *Directory) save() {
+	if d.storePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(d.seen, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(d.storePath, data, 0644); err != nil {
+		log.Printf("Users: failed to write %s: %v", d.storePath, err)
+	}
+}
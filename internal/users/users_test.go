@@ -0,0 +1,52 @@
+package users
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Unit test: Users are merged from the users file, the passwd file and seen users
+func TestDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	usersFile := filepath.Join(dir, "users.json")
+	os.WriteFile(usersFile, []byte(`[{"id": "alice", "name": "Alice", "email": "alice@nas.local"}]`), 0644)
+
+	passwd := filepath.Join(dir, "passwd")
+	os.WriteFile(passwd, []byte(`root:x:0:0:root:/root:/bin/bash
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+alice:x:1000:1000:Alice Liddell,,,:/home/alice:/bin/bash
+bob:x:1001:1001::/home/bob:/bin/bash
+svc:x:1002:1002::/home/svc:/usr/sbin/nologin
+nobody:x:65534:65534:nobody:/nonexistent:/bin/sh
+`), 0644)
+
+	d := NewDirectory(dir, &FileSource{Path: usersFile}, &PasswdSource{Path: passwd})
+	d.Remember(User{ID: "bob", Email: "bob@nas.local"})
+	d.Remember(User{ID: "carol", Name: "Carol"})
+
+	list := d.List()
+	if len(list) != 3 || list[0].ID != "alice" || list[1].ID != "bob" || list[2].ID != "carol" {
+		t.Fatalf("unexpected users %v", list)
+	}
+	if list[0].Name != "Alice" || list[1].Name != "bob" || list[1].Email != "bob@nas.local" {
+		t.Errorf("unexpected merged details %v", list)
+	}
+
+	if found := d.Search("NAS.LOCAL"); len(found) != 2 {
+		t.Errorf("expected 2 users with an email, got %v", found)
+	}
+	if u, ok := d.Lookup("Alice@nas.local"); !ok || u.ID != "alice" {
+		t.Errorf("lookup by email failed: %v %v", u, ok)
+	}
+	if u, ok := d.Lookup("carol"); !ok || u.Handle() != "carol" {
+		t.Errorf("lookup by ID failed: %v %v", u, ok)
+	}
+
+	// Seen users survive a restart
+	reloaded := NewDirectory(dir)
+	if u, ok := reloaded.Lookup("bob"); !ok || u.Email != "bob@nas.local" {
+		t.Errorf("seen users were not persisted: %v %v", u, ok)
+	}
+}